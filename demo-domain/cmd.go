@@ -5,19 +5,18 @@ import (
 	"fmt"
 	"os"
 	"strings"
+)
 
-	"go-base/demo-domain/whois"
+// 命令行模式参数
+var (
+	cmdDomain   = flag.String("domain", "", "要查询的域名")
+	cmdShowFull = flag.Bool("full", false, "是否显示完整WHOIS信息")
 )
 
 // RunCmd 运行命令行模式的查询
 func RunCmd() bool {
-	var domain string
-	var showFull bool
-
-	// 解析命令行参数
-	flag.StringVar(&domain, "domain", "", "要查询的域名")
-	flag.BoolVar(&showFull, "full", false, "是否显示完整WHOIS信息")
-	flag.Parse()
+	domain := *cmdDomain
+	showFull := *cmdShowFull
 
 	// 如果没有提供domain参数，返回false表示不是命令行模式
 	if domain == "" {
@@ -31,7 +30,7 @@ func RunCmd() bool {
 
 	// 执行查询
 	fmt.Printf("正在查询域名: %s\n", domain)
-	result, err := whois.Query(domain)
+	result, err := queryAndSave(domain)
 	if err != nil {
 		fmt.Printf("查询失败: %s\n", err)
 		os.Exit(1)
@@ -50,6 +49,9 @@ func RunCmd() bool {
 			fmt.Println("----------------------------------------")
 			fmt.Println(result.RawText)
 			fmt.Println("----------------------------------------")
+			if result.Truncated {
+				fmt.Println("(响应过大或超时，WHOIS信息已截断)")
+			}
 		}
 	} else {
 		fmt.Printf("状态: 未注册 (可注册)\n")
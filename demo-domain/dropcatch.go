@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"go-base/demo-domain/dropcatch"
+)
+
+// 抢注监控模式参数
+var (
+	dropDomains  = flag.String("dropcatch", "", "监控即将删除的域名，多个域名用逗号分隔")
+	dropFile     = flag.String("dropcatch-file", "", "从文件读取要监控的域名，每行一个")
+	dropInterval = flag.Duration("dropcatch-interval", 10*time.Second, "释放窗口内的轮询间隔")
+)
+
+// RunDropCatch 运行抢注监控模式，持续运行直到所有域名有结果或收到中断信号
+func RunDropCatch() bool {
+	if *dropDomains == "" && *dropFile == "" {
+		return false
+	}
+
+	domains, err := loadDropDomains(*dropDomains, *dropFile)
+	if err != nil {
+		fmt.Printf("读取域名列表失败: %s\n", err)
+		os.Exit(1)
+	}
+	if len(domains) == 0 {
+		fmt.Println("错误: 抢注监控模式需要提供域名")
+		os.Exit(1)
+	}
+
+	startMetricsServer()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	monitor := dropcatch.NewMonitor()
+	monitor.Lookup = queryAndSave
+	monitor.MinInterval = *dropInterval
+
+	fmt.Printf("开始监控 %d 个域名，按 Ctrl+C 退出\n\n", len(domains))
+
+	for event := range monitor.Run(ctx, domains) {
+		ts := event.Time.Format("2006-01-02 15:04:05")
+		switch event.Type {
+		case dropcatch.EventAvailable:
+			fmt.Printf("[%s] %-20s 已释放，可以注册!\n", ts, event.Domain)
+		case dropcatch.EventTracking:
+			fmt.Printf("[%s] %-20s 状态: %s  预计释放: %s  下次查询: %s\n", ts, event.Domain,
+				strings.Join(event.Status, ","),
+				event.EstimatedDrop.Local().Format("2006-01-02 15:04"),
+				event.NextCheck.Format("15:04:05"))
+		case dropcatch.EventRestored:
+			fmt.Printf("[%s] %-20s 已被赎回，停止监控\n", ts, event.Domain)
+		case dropcatch.EventSkipped:
+			fmt.Printf("[%s] %-20s 不在删除周期中 (%s)，跳过\n", ts, event.Domain, strings.Join(event.Status, ","))
+		case dropcatch.EventError:
+			fmt.Printf("[%s] %-20s 查询失败: %s，%s 后重试\n", ts, event.Domain, event.Err,
+				event.NextCheck.Format("15:04:05"))
+		}
+	}
+
+	fmt.Println("\n监控结束。")
+	return true
+}
+
+// loadDropDomains 合并命令行和文件中的域名列表
+func loadDropDomains(list, file string) ([]string, error) {
+	var domains []string
+	for _, domain := range strings.Split(list, ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+
+	if file == "" {
+		return domains, nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains = append(domains, line)
+	}
+	return domains, scanner.Err()
+}
@@ -0,0 +1,231 @@
+// Package dropcatch 监控处于赎回期或待删除状态的域名，在域名释放的第一时间发出通知
+package dropcatch
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go-base/demo-domain/whois"
+)
+
+// 域名在删除周期中的状态
+const (
+	StatusRedemption    = "redemptionPeriod"
+	StatusPendingDelete = "pendingDelete"
+)
+
+// Rule 描述注册局的域名删除周期
+type Rule struct {
+	AutoRenewGrace time.Duration // 到期后的自动续费宽限期
+	Redemption     time.Duration // 赎回期
+	PendingDelete  time.Duration // 待删除期
+	DropHour       int           // 批量删除通常发生的UTC小时，-1表示未知
+}
+
+// 默认使用ICANN通用顶级域的规则
+var defaultRule = Rule{
+	AutoRenewGrace: 45 * 24 * time.Hour,
+	Redemption:     30 * 24 * time.Hour,
+	PendingDelete:  5 * 24 * time.Hour,
+	DropHour:       -1,
+}
+
+// 各注册局的删除周期
+var rules = map[string]Rule{
+	".com": {AutoRenewGrace: 45 * 24 * time.Hour, Redemption: 30 * 24 * time.Hour, PendingDelete: 5 * 24 * time.Hour, DropHour: 19},
+	".net": {AutoRenewGrace: 45 * 24 * time.Hour, Redemption: 30 * 24 * time.Hour, PendingDelete: 5 * 24 * time.Hour, DropHour: 19},
+	".org": {AutoRenewGrace: 45 * 24 * time.Hour, Redemption: 30 * 24 * time.Hour, PendingDelete: 5 * 24 * time.Hour, DropHour: 15},
+	".cn":  {AutoRenewGrace: 30 * 24 * time.Hour, Redemption: 14 * 24 * time.Hour, PendingDelete: 5 * 24 * time.Hour, DropHour: -1},
+}
+
+// RuleFor 返回域名所属注册局的删除周期
+func RuleFor(domain string) Rule {
+	for tld, rule := range rules {
+		if strings.HasSuffix(domain, tld) {
+			return rule
+		}
+	}
+	return defaultRule
+}
+
+// IsDropping 判断WHOIS结果是否显示域名正处于删除周期
+func IsDropping(result *whois.WhoisResult) bool {
+	return result.HasStatus(StatusRedemption) || result.HasStatus(StatusPendingDelete)
+}
+
+// EstimateDrop 根据到期时间、当前状态和注册局规则估算域名释放时间
+func EstimateDrop(result *whois.WhoisResult, now time.Time) time.Time {
+	rule := RuleFor(result.Domain)
+
+	// 根据当前状态确定释放时间的范围
+	earliest, latest := now, now.Add(rule.PendingDelete)
+	if !result.HasStatus(StatusPendingDelete) {
+		earliest = now.Add(rule.PendingDelete)
+		latest = now.Add(rule.Redemption + rule.PendingDelete)
+	}
+
+	// 优先使用到期时间推算，状态范围只用于纠正
+	drop := latest
+	if expiry, err := whois.ParseDate(result.ExpirationDate); err == nil {
+		drop = expiry.Add(rule.AutoRenewGrace + rule.Redemption + rule.PendingDelete)
+	}
+	if drop.Before(earliest) {
+		drop = earliest
+	}
+	if drop.After(latest) {
+		drop = latest
+	}
+
+	// 注册局有固定的删除时间时，对齐到估算时间之后最近的一次删除时间，
+	// 不能提前，否则会过早进入释放窗口
+	if rule.DropHour >= 0 {
+		y, m, d := drop.UTC().Date()
+		aligned := time.Date(y, m, d, rule.DropHour, 0, 0, 0, time.UTC)
+		if aligned.Before(drop) {
+			aligned = aligned.AddDate(0, 0, 1)
+		}
+		drop = aligned
+	}
+	return drop
+}
+
+// EventType 监控事件类型
+type EventType string
+
+const (
+	EventTracking  EventType = "tracking"  // 开始监控或更新预计释放时间
+	EventAvailable EventType = "available" // 域名已释放，可以注册
+	EventRestored  EventType = "restored"  // 域名已被续费赎回，不再监控
+	EventSkipped   EventType = "skipped"   // 域名不在删除周期中，不监控
+	EventError     EventType = "error"     // 查询失败，稍后重试
+)
+
+// Event 监控事件
+type Event struct {
+	Type          EventType
+	Domain        string
+	Time          time.Time
+	Status        []string
+	EstimatedDrop time.Time
+	NextCheck     time.Time
+	Err           error
+}
+
+// Monitor 轮询监控域名，越接近预计释放时间轮询越频繁
+type Monitor struct {
+	Lookup      func(domain string) (*whois.WhoisResult, error) // 查询函数
+	MinInterval time.Duration                                   // 释放窗口内的轮询间隔
+	Window      time.Duration                                   // 预计释放时间前后的密集轮询窗口
+}
+
+// NewMonitor 创建使用默认配置的监控器
+func NewMonitor() *Monitor {
+	return &Monitor{
+		Lookup:      whois.Query,
+		MinInterval: 10 * time.Second,
+		Window:      2 * time.Hour,
+	}
+}
+
+// Run 开始监控域名，所有域名监控结束或ctx取消后关闭事件通道
+func (m *Monitor) Run(ctx context.Context, domains []string) <-chan Event {
+	events := make(chan Event, len(domains))
+
+	var wg sync.WaitGroup
+	for _, domain := range domains {
+		wg.Add(1)
+		go func(domain string) {
+			defer wg.Done()
+			m.watch(ctx, domain, events)
+		}(domain)
+	}
+
+	go func() {
+		wg.Wait()
+		close(events)
+	}()
+	return events
+}
+
+// watch 监控单个域名直到其释放、被赎回或ctx取消
+func (m *Monitor) watch(ctx context.Context, domain string, events chan<- Event) {
+	tracking := false
+	drop := time.Time{}
+
+	for {
+		now := time.Now()
+		event := Event{Domain: domain, Time: now}
+
+		result, err := m.Lookup(domain)
+		switch {
+		case err != nil:
+			event.Type = EventError
+			event.Err = err
+		case !result.IsRegistered:
+			event.Type = EventAvailable
+		case !IsDropping(result):
+			event.Status = result.Status
+			event.Type = EventSkipped
+			if tracking {
+				event.Type = EventRestored
+			}
+		default:
+			tracking = true
+			event.Type = EventTracking
+			event.Status = result.Status
+			drop = EstimateDrop(result, now)
+			event.EstimatedDrop = drop
+		}
+
+		done := event.Type == EventAvailable || event.Type == EventRestored || event.Type == EventSkipped
+		if !done {
+			event.NextCheck = now.Add(m.interval(drop, now))
+		}
+
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			return
+		}
+		if done {
+			return
+		}
+
+		select {
+		case <-time.After(time.Until(event.NextCheck)):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// interval 根据距离预计释放时间的远近计算下一次轮询间隔
+func (m *Monitor) interval(drop, now time.Time) time.Duration {
+	if drop.IsZero() {
+		return time.Minute
+	}
+
+	remaining := drop.Sub(now)
+	switch {
+	case remaining > 3*24*time.Hour:
+		return 12 * time.Hour
+	case remaining > 24*time.Hour:
+		return 2 * time.Hour
+	case remaining > 6*time.Hour:
+		return 30 * time.Minute
+	case remaining > m.Window:
+		// 不要错过窗口的开始
+		if remaining-m.Window < 5*time.Minute {
+			return remaining - m.Window
+		}
+		return 5 * time.Minute
+	case remaining > -m.Window:
+		// 间隔至少1秒，避免未设置 MinInterval 时不停地查询
+		return max(m.MinInterval, time.Second)
+	default:
+		// 预计时间已过仍未释放，估算可能有偏差，保持适中的频率
+		return 10 * time.Minute
+	}
+}
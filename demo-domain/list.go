@@ -6,29 +6,27 @@ import (
 	"os"
 	"strings"
 	"sync"
+)
 
-	"go-base/demo-domain/whois"
+// 列表模式参数
+var (
+	listKeyword = flag.String("list", "", "以列表形式查询关键词在所有支持的域名后缀下的注册状态")
+	listMode    = flag.Bool("showlist", false, "启用列表模式")
 )
 
 // RunList 运行列表模式，直接返回域名是否注册的列表
 func RunList() bool {
-	var keyword string
-	var listMode bool
-
-	// 解析命令行参数
-	flag.StringVar(&keyword, "list", "", "以列表形式查询关键词在所有支持的域名后缀下的注册状态")
-	flag.BoolVar(&listMode, "showlist", false, "启用列表模式")
-	flag.Parse()
+	keyword := *listKeyword
 
 	// 如果没有提供list参数且未启用listMode，返回false表示不是列表模式
-	if keyword == "" && !listMode {
+	if keyword == "" && !*listMode {
 		return false
 	}
 
 	// 如果启用了listMode但没有提供keyword，从其他参数中获取
-	if keyword == "" && listMode {
+	if keyword == "" && *listMode {
 		// 尝试从domain参数获取
-		keyword = flag.Lookup("domain").Value.String()
+		keyword = *cmdDomain
 		if keyword == "" {
 			fmt.Println("错误: 列表模式需要提供关键词")
 			os.Exit(1)
@@ -43,10 +41,6 @@ func RunList() bool {
 		}
 	}
 
-	// 主流域名后缀
-	tlds := []string{".com", ".net", ".org", ".cn", ".io", ".co", ".ai", ".app", 
-		".xyz", ".run", ".me", ".pro", ".top", ".club", ".so"}
-
 	fmt.Printf("关键词 '%s' 的域名注册状态列表:\n\n", keyword)
 	fmt.Println("域名                 状态          注册时间                  注册商")
 	fmt.Println("------------------- ------------- ------------------------- -----------------")
@@ -57,13 +51,13 @@ func RunList() bool {
 	var mu sync.Mutex
 	
 	// 为每个TLD创建一个goroutine进行查询
-	for _, tld := range tlds {
+	for _, tld := range defaultTLDs {
 		wg.Add(1)
 		go func(tld string) {
 			defer wg.Done()
 			domain := keyword + tld
 			
-			result, err := whois.Query(domain)
+			result, err := queryAndSave(domain)
 			
 			mu.Lock()
 			defer mu.Unlock()
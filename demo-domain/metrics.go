@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"go-base/demo-domain/whois"
+)
+
+// 监控指标参数
+var metricsAddr = flag.String("metrics-addr", "", "在指定地址提供 /metrics 监控指标（如 :9100），仅用于抢注监控和交互式模式")
+
+// startMetricsServer 如果指定了地址，在后台启动监控指标服务
+func startMetricsServer() {
+	if *metricsAddr == "" {
+		return
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	if err := whois.RegisterMetrics(reg); err != nil {
+		log.Fatalf("注册监控指标失败: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+
+	go func() {
+		if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+			log.Fatalf("监控指标服务启动失败: %v", err)
+		}
+	}()
+	fmt.Printf("监控指标: http://%s/metrics\n", *metricsAddr)
+}
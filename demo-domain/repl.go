@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/chzyer/readline"
+
+	"go-base/demo-domain/whois"
+)
+
+// REPL支持的命令
+var replCommands = []string{"check", "whois", "list", "search", "tlds", "history", "set", "help", "exit"}
+
+// 支持的输出格式
+var replFormats = []string{"text", "table", "json"}
+
+// repl 交互式命令行的状态
+type repl struct {
+	rl      *readline.Instance
+	format  string
+	history []string
+
+	// 保护输出和会话结果
+	mu      sync.Mutex
+	results map[string]*whois.WhoisResult
+	wg      sync.WaitGroup
+}
+
+// RunREPL 运行交互式模式
+func RunREPL() {
+	historyFile := ""
+	if home, err := os.UserHomeDir(); err == nil {
+		historyFile = filepath.Join(home, ".domain_whois_history")
+	}
+
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:          "whois> ",
+		HistoryFile:     historyFile,
+		AutoComplete:    replCompleter{},
+		InterruptPrompt: "^C",
+		EOFPrompt:       "exit",
+	})
+	if err != nil {
+		fmt.Printf("无法启动交互模式: %s\n", err)
+		os.Exit(1)
+	}
+	defer rl.Close()
+
+	r := &repl{
+		rl:      rl,
+		format:  "text",
+		results: make(map[string]*whois.WhoisResult),
+	}
+
+	startMetricsServer()
+
+	fmt.Fprintln(rl.Stdout(), "域名WHOIS信息查询工具（输入 help 查看命令，Tab 补全域名后缀）")
+
+	for {
+		line, err := rl.Readline()
+		if err == readline.ErrInterrupt {
+			if line == "" {
+				break
+			}
+			continue
+		}
+		if err == io.EOF {
+			break
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		r.history = append(r.history, line)
+
+		if !r.exec(line) {
+			break
+		}
+	}
+
+	// 等待后台查询完成，避免输出被截断
+	r.wg.Wait()
+}
+
+// exec 执行一条命令，返回false表示退出
+func (r *repl) exec(line string) bool {
+	fields := strings.Fields(line)
+	cmd, args := fields[0], fields[1:]
+
+	switch cmd {
+	case "check":
+		if len(args) == 0 {
+			r.printf("用法: check <关键词|域名> ...\n")
+			return true
+		}
+		for _, arg := range args {
+			for _, domain := range expandDomains(arg) {
+				r.lookup(domain, false)
+			}
+		}
+	case "whois":
+		if len(args) != 1 {
+			r.printf("用法: whois <域名>\n")
+			return true
+		}
+		domain := args[0]
+		if !strings.Contains(domain, ".") {
+			domain = domain + ".com"
+		}
+		r.lookup(domain, true)
+	case "list":
+		r.list(args)
+	case "search":
+		filter, err := parseSearchFilter(args)
+		if err != nil {
+			r.printf("%s\n%s", err, searchHelp)
+			return true
+		}
+		db := openStore()
+		if db == nil {
+			r.printf("没有可用的本地存储\n")
+			return true
+		}
+		r.mu.Lock()
+		printRecords(r.rl.Stdout(), db.Search(filter))
+		r.mu.Unlock()
+	case "tlds":
+		r.printf("支持的域名后缀: %s\n", strings.Join(whois.SupportedTLDs(), " "))
+		r.printf("关键词默认查询: %s\n", strings.Join(defaultTLDs, " "))
+	case "history":
+		var b strings.Builder
+		for i, h := range r.history {
+			fmt.Fprintf(&b, "%4d  %s\n", i+1, h)
+		}
+		r.printf("%s", b.String())
+	case "set":
+		if len(args) != 2 || args[0] != "format" || !contains(replFormats, args[1]) {
+			r.printf("用法: set format <%s>\n", strings.Join(replFormats, "|"))
+			return true
+		}
+		r.mu.Lock()
+		r.format = args[1]
+		r.mu.Unlock()
+		r.printf("输出格式: %s\n", args[1])
+	case "help":
+		r.printf("%s", replHelp)
+	case "exit", "quit":
+		return false
+	default:
+		r.printf("未知命令: %s（输入 help 查看命令）\n", cmd)
+	}
+	return true
+}
+
+const replHelp = `命令:
+  check <关键词|域名> ...   后台查询注册状态，关键词会展开为所有默认后缀
+  whois <域名>              查询并显示完整WHOIS信息
+  list [available|registered] 显示本次会话的查询结果
+  search key=value ...      搜索已保存的查询结果（输入 search 查看条件）
+  tlds                      显示支持的域名后缀
+  history                   显示命令历史
+  set format <text|table|json> 设置结果输出格式
+  exit                      退出
+`
+
+// expandDomains 将关键词展开为所有默认后缀下的域名
+func expandDomains(arg string) []string {
+	if strings.Contains(arg, ".") {
+		return []string{arg}
+	}
+	domains := make([]string, 0, len(defaultTLDs))
+	for _, tld := range defaultTLDs {
+		domains = append(domains, arg+tld)
+	}
+	return domains
+}
+
+// lookup 在后台查询域名，结果到达后立即输出
+func (r *repl) lookup(domain string, full bool) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		result, err := queryAndSave(domain)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		if err != nil {
+			fmt.Fprintf(r.rl.Stdout(), "%s 查询失败: %s\n", domain, err)
+			return
+		}
+		r.results[domain] = result
+
+		out := formatResult(result, r.format)
+		if full && result.IsRegistered {
+			out += "\n完整WHOIS信息:\n----------------------------------------\n" +
+				result.RawText + "\n----------------------------------------\n"
+		}
+		fmt.Fprint(r.rl.Stdout(), out)
+	}()
+}
+
+// list 显示本次会话中已查询的结果
+func (r *repl) list(args []string) {
+	filter := ""
+	if len(args) > 0 {
+		filter = args[0]
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	domains := make([]string, 0, len(r.results))
+	for domain := range r.results {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+
+	var b strings.Builder
+	for _, domain := range domains {
+		result := r.results[domain]
+		if filter == "available" && result.IsRegistered || filter == "registered" && !result.IsRegistered {
+			continue
+		}
+		b.WriteString(formatResult(result, "table"))
+	}
+	if b.Len() == 0 {
+		b.WriteString("暂无查询结果\n")
+	}
+	fmt.Fprint(r.rl.Stdout(), b.String())
+}
+
+// printf 输出信息，不打断正在编辑的输入行
+func (r *repl) printf(format string, a ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Fprintf(r.rl.Stdout(), format, a...)
+}
+
+// formatResult 按指定格式渲染查询结果
+func formatResult(result *whois.WhoisResult, format string) string {
+	switch format {
+	case "table":
+		if !result.IsRegistered {
+			return fmt.Sprintf("%-20s %-13s %-25s %-20s\n", result.Domain, "未注册", "-", "-")
+		}
+		registrar := result.Registrar
+		if len(registrar) > 25 {
+			registrar = registrar[:22] + "..."
+		}
+		return fmt.Sprintf("%-20s %-13s %-25s %-20s\n", result.Domain, "已注册", result.CreationDate, registrar)
+	case "json":
+		data, _ := json.Marshal(map[string]interface{}{
+			"domain":          result.Domain,
+			"registered":      result.IsRegistered,
+			"creation_date":   result.CreationDate,
+			"expiration_date": result.ExpirationDate,
+			"registrant":      result.Registrant,
+			"registrar":       result.Registrar,
+		})
+		return string(data) + "\n"
+	default:
+		if !result.IsRegistered {
+			return fmt.Sprintf("%s\n  状态: 未注册 (可注册)\n", result.Domain)
+		}
+		return fmt.Sprintf("%s\n  状态: 已注册\n  注册时间: %s\n  到期时间: %s\n  注册人: %s\n  注册商: %s\n",
+			result.Domain, result.CreationDate, result.ExpirationDate, result.Registrant, result.Registrar)
+	}
+}
+
+// replCompleter 为命令和域名后缀提供Tab补全
+type replCompleter struct{}
+
+// Do 实现 readline.AutoCompleter 接口
+func (replCompleter) Do(line []rune, pos int) ([][]rune, int) {
+	input := string(line[:pos])
+	fields := strings.Fields(input)
+
+	// 当前正在输入的单词
+	word := ""
+	if len(fields) > 0 && !strings.HasSuffix(input, " ") {
+		word = fields[len(fields)-1]
+		fields = fields[:len(fields)-1]
+	}
+
+	var candidates []string
+	switch {
+	case len(fields) == 0:
+		candidates = replCommands
+	case fields[0] == "set" && len(fields) == 1:
+		candidates = []string{"format"}
+	case fields[0] == "set" && len(fields) == 2:
+		candidates = replFormats
+	case fields[0] == "list" && len(fields) == 1:
+		candidates = []string{"available", "registered"}
+	case fields[0] == "search":
+		candidates = []string{"registrar=", "registrant=", "ns=", "status=", "expires="}
+	case fields[0] == "check" || fields[0] == "whois":
+		// 输入 "google." 后补全域名后缀
+		if i := strings.LastIndex(word, "."); i >= 0 {
+			for _, tld := range whois.SupportedTLDs() {
+				candidates = append(candidates, word[:i]+tld)
+			}
+		}
+	}
+
+	var matches [][]rune
+	for _, c := range candidates {
+		if strings.HasPrefix(c, word) {
+			matches = append(matches, []rune(c[len(word):]+" "))
+		}
+	}
+	return matches, len([]rune(word))
+}
+
+// contains 判断字符串切片是否包含指定值
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go-base/demo-domain/store"
+	"go-base/demo-domain/whois"
+)
+
+// 本地存储和搜索参数
+var (
+	storePath  = flag.String("store", "", "保存查询结果的文件（如 ~/.domain_whois_store.jsonl），默认不保存")
+	searchMode = flag.Bool("search", false, "搜索已保存的查询结果，条件以 key=value 形式跟在参数后")
+)
+
+var (
+	storeOnce sync.Once
+	resultDB  *store.Store
+)
+
+// openStore 打开本地存储，未配置或打开失败时返回nil
+func openStore() *store.Store {
+	storeOnce.Do(func() {
+		if *storePath == "" {
+			return
+		}
+		db, err := store.Open(*storePath)
+		if err != nil {
+			fmt.Printf("警告: %s，查询结果不会被保存\n", err)
+			return
+		}
+		resultDB = db
+	})
+	return resultDB
+}
+
+// queryAndSave 查询域名并保存结果
+func queryAndSave(domain string) (*whois.WhoisResult, error) {
+	result, err := whois.Query(domain)
+	if err != nil {
+		return nil, err
+	}
+	if db := openStore(); db != nil {
+		if err := db.Put(result); err != nil {
+			fmt.Printf("警告: 保存 %s 的查询结果失败: %s\n", domain, err)
+		}
+	}
+	return result, nil
+}
+
+const searchHelp = `搜索条件（可组合，均为 key=value 形式）:
+  registrar=名称        注册商包含该名称
+  registrant=名称       注册人包含该名称
+  ns=ns1,ns2            同时使用这些域名服务器
+  status=状态           处于该状态，如 clientTransferProhibited
+  expires=起始..结束    到期时间范围（YYYY-MM-DD），起止可省略其一
+`
+
+// RunSearch 运行搜索模式
+func RunSearch() bool {
+	if !*searchMode {
+		return false
+	}
+
+	filter, err := parseSearchFilter(flag.Args())
+	if err != nil {
+		fmt.Printf("错误: %s\n\n%s", err, searchHelp)
+		os.Exit(1)
+	}
+
+	db := openStore()
+	if db == nil {
+		fmt.Println("错误: 没有可用的本地存储，请使用 -store 指定保存查询结果的文件")
+		os.Exit(1)
+	}
+
+	printRecords(os.Stdout, db.Search(filter))
+	return true
+}
+
+// parseSearchFilter 解析 key=value 形式的搜索条件
+func parseSearchFilter(args []string) (store.Filter, error) {
+	var filter store.Filter
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			return filter, fmt.Errorf("无效的搜索条件: %s", arg)
+		}
+
+		key, value := parts[0], parts[1]
+		switch key {
+		case "registrar":
+			filter.Registrar = value
+		case "registrant":
+			filter.Registrant = value
+		case "ns":
+			filter.NameServers = strings.Split(strings.ToLower(value), ",")
+		case "status":
+			filter.Status = value
+		case "expires":
+			from, to, _ := strings.Cut(value, "..")
+			var err error
+			if from != "" {
+				if filter.ExpiresAfter, err = time.Parse("2006-01-02", from); err != nil {
+					return filter, fmt.Errorf("无效的日期: %s", from)
+				}
+			}
+			if to != "" {
+				if filter.ExpiresBefore, err = time.Parse("2006-01-02", to); err != nil {
+					return filter, fmt.Errorf("无效的日期: %s", to)
+				}
+				// 结束日期当天也包含在内
+				filter.ExpiresBefore = filter.ExpiresBefore.AddDate(0, 0, 1)
+			}
+		default:
+			return filter, fmt.Errorf("未知的搜索条件: %s", key)
+		}
+	}
+	return filter, nil
+}
+
+// printRecords 以表格形式输出搜索结果
+func printRecords(w io.Writer, records []*store.Record) {
+	var b strings.Builder
+	fmt.Fprintln(&b, "域名                 到期时间     注册商                    域名服务器")
+	fmt.Fprintln(&b, "------------------- ------------ ------------------------- -----------------")
+	for _, record := range records {
+		expiry := "-"
+		if !record.Expiry.IsZero() {
+			expiry = record.Expiry.Format("2006-01-02")
+		}
+		registrar := record.Registrar
+		if !record.IsRegistered {
+			registrar = "(未注册)"
+		}
+		if len(registrar) > 25 {
+			registrar = registrar[:22] + "..."
+		}
+		fmt.Fprintf(&b, "%-20s %-12s %-25s %s\n", record.Domain, expiry, registrar, strings.Join(record.NameServers, ","))
+	}
+	fmt.Fprintf(&b, "\n共 %d 个域名。\n", len(records))
+	fmt.Fprint(w, b.String())
+}
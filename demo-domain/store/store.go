@@ -0,0 +1,280 @@
+// Package store 在本地保存WHOIS查询结果，并按注册商、注册人、域名服务器、状态和到期时间建立索引
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-base/demo-domain/whois"
+)
+
+// Record 保存的查询结果
+type Record struct {
+	whois.WhoisResult
+	CheckedAt time.Time // 查询时间
+	Expiry    time.Time // 解析后的到期时间，无法解析时为零值
+}
+
+// Filter 搜索条件，空字段表示不限制
+type Filter struct {
+	Registrar     string    // 注册商，不区分大小写的部分匹配
+	Registrant    string    // 注册人，不区分大小写的部分匹配
+	NameServers   []string  // 必须同时使用这些域名服务器
+	Status        string    // 域名状态，如 clientTransferProhibited
+	ExpiresAfter  time.Time // 到期时间不早于
+	ExpiresBefore time.Time // 到期时间早于
+}
+
+// Store 以追加写入的JSON Lines文件保存查询结果，同一域名以最后一次查询为准
+type Store struct {
+	mu   sync.RWMutex
+	file *os.File
+
+	records      map[string]*Record
+	byRegistrar  index
+	byRegistrant index
+	byNameServer index
+	byStatus     index
+	byExpiry     []*Record // 按到期时间排序，为nil时需要重建
+}
+
+// index 从归一化的字段值到域名集合的映射
+type index map[string]map[string]bool
+
+func (idx index) add(key, domain string) {
+	key = normalize(key)
+	if key == "" {
+		return
+	}
+	if idx[key] == nil {
+		idx[key] = make(map[string]bool)
+	}
+	idx[key][domain] = true
+}
+
+func (idx index) remove(key, domain string) {
+	key = normalize(key)
+	delete(idx[key], domain)
+	if len(idx[key]) == 0 {
+		delete(idx, key)
+	}
+}
+
+// match 返回字段值包含substr的所有域名
+func (idx index) match(substr string) map[string]bool {
+	substr = normalize(substr)
+	domains := make(map[string]bool)
+	for key, set := range idx {
+		if strings.Contains(key, substr) {
+			for domain := range set {
+				domains[domain] = true
+			}
+		}
+	}
+	return domains
+}
+
+// exact 返回字段值等于key的所有域名
+func (idx index) exact(key string) map[string]bool {
+	domains := make(map[string]bool)
+	for domain := range idx[normalize(key)] {
+		domains[domain] = true
+	}
+	return domains
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// Open 打开或创建存储文件并加载已有记录
+func Open(path string) (*Store, error) {
+	s := &Store{
+		records:      make(map[string]*Record),
+		byRegistrar:  make(index),
+		byRegistrant: make(index),
+		byNameServer: make(index),
+		byStatus:     make(index),
+	}
+
+	if err := s.load(path); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打开存储文件失败: %w", err)
+	}
+	s.file = file
+	return s, nil
+}
+
+// load 读取存储文件，跳过无法解析的行
+func (s *Store) load(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取存储文件失败: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		s.index(&record)
+	}
+	return scanner.Err()
+}
+
+// Close 关闭存储文件
+func (s *Store) Close() error {
+	return s.file.Close()
+}
+
+// Put 保存一次查询结果
+func (s *Store) Put(result *whois.WhoisResult) error {
+	record := &Record{
+		WhoisResult: *result,
+		CheckedAt:   time.Now(),
+	}
+	if expiry, err := whois.ParseDate(result.ExpirationDate); err == nil {
+		record.Expiry = expiry
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("写入存储文件失败: %w", err)
+	}
+	s.index(record)
+	return nil
+}
+
+// index 将记录加入索引，替换同一域名的旧记录
+func (s *Store) index(record *Record) {
+	domain := normalize(record.Domain)
+	if old, ok := s.records[domain]; ok {
+		s.byRegistrar.remove(old.Registrar, domain)
+		s.byRegistrant.remove(old.Registrant, domain)
+		for _, ns := range old.NameServers {
+			s.byNameServer.remove(ns, domain)
+		}
+		for _, status := range old.Status {
+			s.byStatus.remove(status, domain)
+		}
+	}
+
+	s.records[domain] = record
+	s.byRegistrar.add(record.Registrar, domain)
+	s.byRegistrant.add(record.Registrant, domain)
+	for _, ns := range record.NameServers {
+		s.byNameServer.add(ns, domain)
+	}
+	for _, status := range record.Status {
+		s.byStatus.add(status, domain)
+	}
+	s.byExpiry = nil
+}
+
+// Len 返回保存的域名数量
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.records)
+}
+
+// Search 返回满足所有条件的记录，按到期时间排序
+func (s *Store) Search(f Filter) []*Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 依次用索引缩小候选集合，nil表示尚未限制
+	var candidates map[string]bool
+	narrow := func(set map[string]bool) {
+		if candidates == nil {
+			candidates = set
+			return
+		}
+		for domain := range candidates {
+			if !set[domain] {
+				delete(candidates, domain)
+			}
+		}
+	}
+
+	if f.Registrar != "" {
+		narrow(s.byRegistrar.match(f.Registrar))
+	}
+	if f.Registrant != "" {
+		narrow(s.byRegistrant.match(f.Registrant))
+	}
+	for _, ns := range f.NameServers {
+		narrow(s.byNameServer.exact(strings.TrimSuffix(ns, ".")))
+	}
+	if f.Status != "" {
+		narrow(s.byStatus.exact(f.Status))
+	}
+
+	// 到期时间范围使用有序切片二分查找
+	s.sortByExpiry()
+	lo, hi := 0, len(s.byExpiry)
+	if !f.ExpiresAfter.IsZero() {
+		lo = sort.Search(len(s.byExpiry), func(i int) bool {
+			return !s.byExpiry[i].Expiry.Before(f.ExpiresAfter)
+		})
+	}
+	if !f.ExpiresBefore.IsZero() {
+		hi = sort.Search(len(s.byExpiry), func(i int) bool {
+			return !s.byExpiry[i].Expiry.Before(f.ExpiresBefore)
+		})
+	}
+	hasRange := !f.ExpiresAfter.IsZero() || !f.ExpiresBefore.IsZero()
+
+	var results []*Record
+	for i, record := range s.byExpiry {
+		if hasRange && (i < lo || i >= hi || record.Expiry.IsZero()) {
+			continue
+		}
+		if candidates != nil && !candidates[normalize(record.Domain)] {
+			continue
+		}
+		results = append(results, record)
+	}
+	return results
+}
+
+// sortByExpiry 在需要时重建到期时间排序，未知到期时间排在最前
+func (s *Store) sortByExpiry() {
+	if s.byExpiry != nil {
+		return
+	}
+	s.byExpiry = make([]*Record, 0, len(s.records))
+	for _, record := range s.records {
+		s.byExpiry = append(s.byExpiry, record)
+	}
+	sort.Slice(s.byExpiry, func(i, j int) bool {
+		a, b := s.byExpiry[i], s.byExpiry[j]
+		if !a.Expiry.Equal(b.Expiry) {
+			return a.Expiry.Before(b.Expiry)
+		}
+		return a.Domain < b.Domain
+	})
+}
@@ -0,0 +1,76 @@
+package whois
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// 查询结果分类
+const (
+	OutcomeAvailable  = "available"
+	OutcomeRegistered = "registered"
+	OutcomeError      = "error"
+	OutcomeTimeout    = "timeout"
+)
+
+var (
+	lookupsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "whois_lookups_total",
+		Help: "WHOIS查询次数，按服务器和结果分类",
+	}, []string{"server", "outcome"})
+
+	lookupDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "whois_lookup_duration_seconds",
+		Help:    "WHOIS查询耗时，按服务器和结果分类",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 15},
+	}, []string{"server", "outcome"})
+
+	truncatedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "whois_truncated_responses_total",
+		Help: "被截断的WHOIS响应次数",
+	}, []string{"server"})
+)
+
+// RegisterMetrics 将WHOIS查询指标注册到reg
+func RegisterMetrics(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{lookupsTotal, lookupDuration, truncatedTotal} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// observeLookup 记录一次查询的耗时和结果
+func observeLookup(addr string, start time.Time, result *WhoisResult, err error) {
+	server := addr
+	if host, _, splitErr := net.SplitHostPort(addr); splitErr == nil {
+		server = host
+	}
+
+	outcome := lookupOutcome(result, err)
+	lookupsTotal.WithLabelValues(server, outcome).Inc()
+	lookupDuration.WithLabelValues(server, outcome).Observe(time.Since(start).Seconds())
+
+	if result != nil && result.Truncated {
+		truncatedTotal.WithLabelValues(server).Inc()
+	}
+}
+
+// lookupOutcome 将查询结果归类
+func lookupOutcome(result *WhoisResult, err error) string {
+	var netErr net.Error
+	switch {
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return OutcomeTimeout
+	case err != nil:
+		return OutcomeError
+	case result.IsRegistered:
+		return OutcomeRegistered
+	default:
+		return OutcomeAvailable
+	}
+}
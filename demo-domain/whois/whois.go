@@ -1,10 +1,12 @@
 package whois
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 )
@@ -17,7 +19,52 @@ type WhoisResult struct {
 	ExpirationDate string
 	Registrant     string
 	Registrar      string
+	Status         []string // 域名状态，如 clientTransferProhibited、redemptionPeriod
+	NameServers    []string // 域名服务器（小写）
 	RawText        string
+	Truncated      bool // 响应超过大小限制或总超时，RawText不完整
+}
+
+// Client WHOIS查询客户端，限制连接、读取的耗时和响应大小
+type Client struct {
+	DialTimeout     time.Duration // 连接超时
+	ReadTimeout     time.Duration // 单次读取超时，已收到数据后超时视为服务器保持连接不关闭，响应结束
+	TotalTimeout    time.Duration // 整个查询的超时
+	MaxResponseSize int           // 响应最大字节数，超出部分被丢弃
+}
+
+// 客户端的默认限制，Client中未设置（≤0）的字段使用这些值
+const (
+	defaultDialTimeout     = 5 * time.Second
+	defaultReadTimeout     = 5 * time.Second
+	defaultTotalTimeout    = 15 * time.Second
+	defaultMaxResponseSize = 1 << 20
+)
+
+// DefaultClient Query使用的默认客户端
+var DefaultClient = &Client{
+	DialTimeout:     defaultDialTimeout,
+	ReadTimeout:     defaultReadTimeout,
+	TotalTimeout:    defaultTotalTimeout,
+	MaxResponseSize: defaultMaxResponseSize,
+}
+
+// withDefaults 返回未设置的字段使用默认值后的副本，零值的Client也可以直接使用
+func (c *Client) withDefaults() *Client {
+	d := *c
+	if d.DialTimeout <= 0 {
+		d.DialTimeout = defaultDialTimeout
+	}
+	if d.ReadTimeout <= 0 {
+		d.ReadTimeout = defaultReadTimeout
+	}
+	if d.TotalTimeout <= 0 {
+		d.TotalTimeout = defaultTotalTimeout
+	}
+	if d.MaxResponseSize <= 0 {
+		d.MaxResponseSize = defaultMaxResponseSize
+	}
+	return &d
 }
 
 // WHOIS服务器映射表
@@ -40,8 +87,23 @@ var whoisServers = map[string]string{
 	".so":   "whois.nic.so",
 }
 
-// Query 查询域名的WHOIS信息
+// SupportedTLDs 返回支持查询的域名后缀列表（已排序）
+func SupportedTLDs() []string {
+	tlds := make([]string, 0, len(whoisServers))
+	for tld := range whoisServers {
+		tlds = append(tlds, tld)
+	}
+	sort.Strings(tlds)
+	return tlds
+}
+
+// Query 使用默认客户端查询域名的WHOIS信息
 func Query(domain string) (*WhoisResult, error) {
+	return DefaultClient.Query(domain)
+}
+
+// Query 查询域名的WHOIS信息
+func (c *Client) Query(domain string) (*WhoisResult, error) {
 	// 确定WHOIS服务器
 	var server string
 	for tld, srv := range whoisServers {
@@ -55,37 +117,46 @@ func Query(domain string) (*WhoisResult, error) {
 		return nil, fmt.Errorf("不支持的域名后缀")
 	}
 
+	return c.QueryServer(domain, server+":43")
+}
+
+// QueryServer 向指定地址的WHOIS服务器查询域名
+func (c *Client) QueryServer(domain, addr string) (*WhoisResult, error) {
+	start := time.Now()
+	result, err := c.queryServer(domain, addr)
+	observeLookup(addr, start, result, err)
+	return result, err
+}
+
+func (c *Client) queryServer(domain, addr string) (*WhoisResult, error) {
+	c = c.withDefaults()
+	deadline := time.Now().Add(c.TotalTimeout)
+
 	// 连接WHOIS服务器
-	conn, err := net.DialTimeout("tcp", server+":43", 5*time.Second)
+	conn, err := net.DialTimeout("tcp", addr, c.DialTimeout)
 	if err != nil {
 		return nil, fmt.Errorf("连接WHOIS服务器失败: %w", err)
 	}
 	defer conn.Close()
 
 	// 发送查询请求
+	conn.SetWriteDeadline(deadline)
 	_, err = conn.Write([]byte(domain + "\r\n"))
 	if err != nil {
 		return nil, fmt.Errorf("发送查询请求失败: %w", err)
 	}
 
 	// 读取响应
-	buffer := make([]byte, 0, 4096)
-	tmp := make([]byte, 1024)
-	for {
-		n, err := conn.Read(tmp)
-		if err != nil {
-			if err != io.EOF {
-				return nil, fmt.Errorf("读取响应失败: %w", err)
-			}
-			break
-		}
-		buffer = append(buffer, tmp[:n]...)
+	buffer, truncated, err := c.readResponse(conn, deadline)
+	if err != nil {
+		return nil, err
 	}
 
 	rawText := string(buffer)
 	result := &WhoisResult{
-		Domain:  domain,
-		RawText: rawText,
+		Domain:    domain,
+		RawText:   rawText,
+		Truncated: truncated,
 	}
 
 	// 解析响应
@@ -94,6 +165,48 @@ func Query(domain string) (*WhoisResult, error) {
 	return result, nil
 }
 
+// readResponse 读取响应直到服务器关闭连接、空闲超时、总超时或超出大小限制
+func (c *Client) readResponse(conn net.Conn, deadline time.Time) ([]byte, bool, error) {
+	buffer := make([]byte, 0, 4096)
+	tmp := make([]byte, 4096)
+	for {
+		// 每次读取的超时不能超过总超时
+		readDeadline := time.Now().Add(c.ReadTimeout)
+		if readDeadline.After(deadline) {
+			readDeadline = deadline
+		}
+		conn.SetReadDeadline(readDeadline)
+
+		n, err := conn.Read(tmp)
+		if n > 0 {
+			if remaining := c.MaxResponseSize - len(buffer); n > remaining {
+				return append(buffer, tmp[:remaining]...), true, nil
+			}
+			buffer = append(buffer, tmp[:n]...)
+		}
+		if err == nil {
+			continue
+		}
+		if err == io.EOF {
+			return buffer, false, nil
+		}
+
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			if len(buffer) == 0 {
+				return nil, false, fmt.Errorf("读取响应超时: %w", err)
+			}
+			// 已收到数据但服务器不关闭连接
+			if time.Now().Before(deadline) {
+				return buffer, false, nil
+			}
+			// 总超时时服务器仍在发送数据
+			return buffer, true, nil
+		}
+		return nil, false, fmt.Errorf("读取响应失败: %w", err)
+	}
+}
+
 // parseResult 解析WHOIS响应文本
 func parseResult(result *WhoisResult) {
 	// 检查是否已注册
@@ -158,6 +271,24 @@ func parseResult(result *WhoisResult) {
 		}
 	}
 
+	// 解析域名状态，一个域名可能有多条状态
+	statusRe := regexp.MustCompile(`(?im)^\s*(?:Domain Status|Status):\s*(\S+)`)
+	for _, matches := range statusRe.FindAllStringSubmatch(result.RawText, -1) {
+		status := strings.TrimSpace(matches[1])
+		if !containsFold(result.Status, status) {
+			result.Status = append(result.Status, status)
+		}
+	}
+
+	// 解析域名服务器
+	nsRe := regexp.MustCompile(`(?im)^\s*(?:Name Server|nserver):\s*(\S+)`)
+	for _, matches := range nsRe.FindAllStringSubmatch(result.RawText, -1) {
+		ns := strings.TrimSuffix(strings.ToLower(matches[1]), ".")
+		if !containsFold(result.NameServers, ns) {
+			result.NameServers = append(result.NameServers, ns)
+		}
+	}
+
 	// 解析注册商
 	registrarPatterns := []string{
 		`(?i)Registrar: (.+)`,
@@ -172,4 +303,43 @@ func parseResult(result *WhoisResult) {
 			break
 		}
 	}
-} 
\ No newline at end of file
+}
+
+// HasStatus 判断域名是否处于指定状态（不区分大小写）
+func (r *WhoisResult) HasStatus(status string) bool {
+	return containsFold(r.Status, status)
+}
+
+// containsFold 判断列表中是否包含指定值（不区分大小写）
+func containsFold(list []string, value string) bool {
+	for _, s := range list {
+		if strings.EqualFold(s, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// 不同WHOIS服务器使用的日期格式
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02",
+	"02-Jan-2006",
+	"2006.01.02",
+	"2006/01/02",
+}
+
+// ParseDate 解析WHOIS信息中的日期，无时区信息时按UTC处理
+func ParseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法解析日期: %s", value)
+}
@@ -0,0 +1,159 @@
+package main
+
+// 使用本地的异常WHOIS服务器验证客户端的超时和大小限制
+//
+// 用法: go run ./whoistest
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"go-base/demo-domain/whois"
+)
+
+const response = "Domain Name: EXAMPLE.COM\r\nRegistrar: Example Registrar\r\nCreation Date: 1995-08-14T04:00:00Z\r\n"
+
+// 测试场景：服务器的行为和期望的查询结果
+type testCase struct {
+	Name          string
+	Serve         func(conn net.Conn)
+	WantErr       bool
+	WantTruncated bool
+	WantContains  string
+	MaxElapsed    time.Duration
+}
+
+func main() {
+	client := &whois.Client{
+		DialTimeout:     time.Second,
+		ReadTimeout:     200 * time.Millisecond,
+		TotalTimeout:    time.Second,
+		MaxResponseSize: 64 * 1024,
+	}
+
+	testCases := []testCase{
+		{
+			Name: "正常响应后关闭连接",
+			Serve: func(conn net.Conn) {
+				conn.Write([]byte(response))
+			},
+			WantContains: "Example Registrar",
+			MaxElapsed:   200 * time.Millisecond,
+		},
+		{
+			Name: "响应后保持连接不关闭",
+			Serve: func(conn net.Conn) {
+				conn.Write([]byte(response))
+				time.Sleep(5 * time.Second)
+			},
+			WantContains: "Example Registrar",
+			MaxElapsed:   500 * time.Millisecond,
+		},
+		{
+			Name: "接受连接但不发送数据",
+			Serve: func(conn net.Conn) {
+				time.Sleep(5 * time.Second)
+			},
+			WantErr:    true,
+			MaxElapsed: 500 * time.Millisecond,
+		},
+		{
+			Name: "持续缓慢发送数据",
+			Serve: func(conn net.Conn) {
+				conn.Write([]byte(response))
+				for i := 0; i < 100; i++ {
+					if _, err := conn.Write([]byte("% padding\r\n")); err != nil {
+						return
+					}
+					time.Sleep(50 * time.Millisecond)
+				}
+			},
+			WantTruncated: true,
+			WantContains:  "Example Registrar",
+			MaxElapsed:    1500 * time.Millisecond,
+		},
+		{
+			Name: "发送超大响应",
+			Serve: func(conn net.Conn) {
+				conn.Write([]byte(response))
+				chunk := []byte(strings.Repeat("x", 4096))
+				for {
+					if _, err := conn.Write(chunk); err != nil {
+						return
+					}
+				}
+			},
+			WantTruncated: true,
+			WantContains:  "Example Registrar",
+			MaxElapsed:    time.Second,
+		},
+	}
+
+	failed := 0
+	for _, tc := range testCases {
+		if err := run(client, tc); err != nil {
+			fmt.Printf("FAIL %s: %s\n", tc.Name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("PASS %s\n", tc.Name)
+	}
+
+	if failed > 0 {
+		fmt.Printf("\n%d 个场景失败\n", failed)
+		os.Exit(1)
+	}
+	fmt.Println("\n所有场景通过。")
+}
+
+// run 启动本地服务器执行一个测试场景
+func run(client *whois.Client, tc testCase) error {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// 读取查询请求
+		buf := make([]byte, 256)
+		conn.Read(buf)
+		tc.Serve(conn)
+	}()
+
+	start := time.Now()
+	result, err := client.QueryServer("example.com", ln.Addr().String())
+	elapsed := time.Since(start)
+
+	if elapsed > tc.MaxElapsed {
+		return fmt.Errorf("耗时 %s，超过 %s", elapsed, tc.MaxElapsed)
+	}
+	if tc.WantErr {
+		if err == nil {
+			return fmt.Errorf("期望返回错误")
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("查询失败: %w", err)
+	}
+	if result.Truncated != tc.WantTruncated {
+		return fmt.Errorf("Truncated = %v，期望 %v", result.Truncated, tc.WantTruncated)
+	}
+	if len(result.RawText) > client.MaxResponseSize {
+		return fmt.Errorf("响应大小 %d 超过限制 %d", len(result.RawText), client.MaxResponseSize)
+	}
+	if !strings.Contains(result.RawText, tc.WantContains) {
+		return fmt.Errorf("响应中缺少 %q", tc.WantContains)
+	}
+	return nil
+}
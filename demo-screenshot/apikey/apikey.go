@@ -0,0 +1,332 @@
+// Package apikey 管理截图服务的API密钥，提供按密钥的速率限制和每日配额
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrInvalidKey 密钥不存在或已被吊销
+var ErrInvalidKey = errors.New("无效的API密钥")
+
+// ErrNotFound 指定ID的密钥不存在
+var ErrNotFound = errors.New("密钥不存在")
+
+// LimitError 超过速率限制或每日配额
+type LimitError struct {
+	Reason     string
+	RetryAfter time.Duration // 多久之后可以重试
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s，请在 %d 秒后重试", e.Reason, int(e.RetryAfter.Seconds()+0.999))
+}
+
+// Key 一个API密钥，只保存密钥的哈希值，密钥本身只在创建时返回一次
+type Key struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Hash       string     `json:"hash,omitempty"`
+	RateLimit  int        `json:"rate_limit"`  // 每分钟最多请求数，0表示不限制
+	DailyQuota int        `json:"daily_quota"` // 每天最多请求数（UTC），0表示不限制
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Usage 密钥的使用情况
+type Usage struct {
+	Day      string    `json:"day"`   // 当天日期（UTC），跨天后Today清零
+	Today    int       `json:"today"` // 当天的请求数
+	Total    int64     `json:"total"`
+	LastUsed time.Time `json:"last_used,omitempty"`
+}
+
+// KeyInfo 密钥和使用情况，用于管理接口
+type KeyInfo struct {
+	Key
+	Usage Usage `json:"usage"`
+}
+
+// storeFile 持久化的文件内容
+type storeFile struct {
+	Keys  []*Key            `json:"keys"`
+	Usage map[string]*Usage `json:"usage"`
+}
+
+// Store 保存在本地JSON文件中的密钥和使用情况
+type Store struct {
+	path string
+
+	mu       sync.Mutex
+	keys     map[string]*Key // 按ID索引
+	byHash   map[string]*Key
+	usage    map[string]*Usage
+	limiters map[string]*limiter // 速率限制只保存在内存中
+	dirty    bool
+	stop     chan struct{}
+	done     chan struct{}
+	now      func() time.Time
+}
+
+// 使用情况写入文件的间隔
+const flushInterval = 10 * time.Second
+
+// Open 打开密钥文件，文件不存在时创建空的密钥库
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path:     path,
+		keys:     make(map[string]*Key),
+		byHash:   make(map[string]*Key),
+		usage:    make(map[string]*Usage),
+		limiters: make(map[string]*limiter),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		now:      time.Now,
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("读取密钥文件失败: %w", err)
+	}
+	if len(data) > 0 {
+		var f storeFile
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("解析密钥文件失败: %w", err)
+		}
+		for _, k := range f.Keys {
+			s.keys[k.ID] = k
+			s.byHash[k.Hash] = k
+		}
+		for id, u := range f.Usage {
+			s.usage[id] = u
+		}
+	}
+
+	go s.flushLoop()
+	return s, nil
+}
+
+// Create 创建新密钥，返回的密钥明文只有这一次机会获取
+func (s *Store) Create(name string, rateLimit, dailyQuota int) (*Key, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// ID只有4个字节，重新生成直到不与已有的密钥（包括已吊销的）重复
+	var id string
+	for id == "" || s.keys[id] != nil {
+		var err error
+		if id, err = randomHex(4); err != nil {
+			return nil, "", err
+		}
+	}
+	secretPart, err := randomHex(16)
+	if err != nil {
+		return nil, "", err
+	}
+	secret := "sk_" + id + "_" + secretPart
+
+	key := &Key{
+		ID:         id,
+		Name:       name,
+		Hash:       hashKey(secret),
+		RateLimit:  rateLimit,
+		DailyQuota: dailyQuota,
+		CreatedAt:  s.now().UTC(),
+	}
+
+	s.keys[id] = key
+	s.byHash[key.Hash] = key
+	if err := s.saveLocked(); err != nil {
+		delete(s.keys, id)
+		delete(s.byHash, key.Hash)
+		return nil, "", err
+	}
+	return key, secret, nil
+}
+
+// Revoke 吊销密钥，吊销后的密钥仍然保留使用记录
+func (s *Store) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if key.RevokedAt == nil {
+		now := s.now().UTC()
+		key.RevokedAt = &now
+	}
+	delete(s.limiters, id)
+	return s.saveLocked()
+}
+
+// Get 返回密钥和使用情况
+func (s *Store) Get(id string) (*KeyInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return s.infoLocked(key), nil
+}
+
+// List 返回所有密钥和使用情况，按创建时间排序
+func (s *Store) List() []*KeyInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]*KeyInfo, 0, len(s.keys))
+	for _, key := range s.keys {
+		list = append(list, s.infoLocked(key))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+func (s *Store) infoLocked(key *Key) *KeyInfo {
+	info := &KeyInfo{Key: *key}
+	info.Hash = ""
+	if u, ok := s.usage[key.ID]; ok {
+		info.Usage = *u
+		if info.Usage.Day != day(s.now()) {
+			info.Usage.Today = 0
+		}
+	}
+	return info
+}
+
+// Authorize 校验密钥并记录一次请求，超过速率限制或每日配额时返回 *LimitError
+func (s *Store) Authorize(secret string) (*KeyInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.byHash[hashKey(secret)]
+	if !ok || key.RevokedAt != nil {
+		return nil, ErrInvalidKey
+	}
+	now := s.now()
+
+	u, ok := s.usage[key.ID]
+	if !ok {
+		u = &Usage{}
+		s.usage[key.ID] = u
+	}
+	if today := day(now); u.Day != today {
+		u.Day, u.Today = today, 0
+	}
+	if key.DailyQuota > 0 && u.Today >= key.DailyQuota {
+		return nil, &LimitError{Reason: "已超过每日配额", RetryAfter: untilTomorrow(now)}
+	}
+
+	if key.RateLimit > 0 {
+		l, ok := s.limiters[key.ID]
+		if !ok || l.limit != key.RateLimit {
+			l = newLimiter(key.RateLimit, now)
+			s.limiters[key.ID] = l
+		}
+		if wait := l.take(now); wait > 0 {
+			return nil, &LimitError{Reason: "请求过于频繁", RetryAfter: wait}
+		}
+	}
+
+	u.Today++
+	u.Total++
+	u.LastUsed = now.UTC()
+	s.dirty = true
+	return s.infoLocked(key), nil
+}
+
+// Close 把使用情况写入文件并停止后台任务
+func (s *Store) Close() error {
+	close(s.stop)
+	<-s.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.dirty {
+		return nil
+	}
+	return s.saveLocked()
+}
+
+// flushLoop 定期把使用情况写入文件，避免每次请求都写文件
+func (s *Store) flushLoop() {
+	defer close(s.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			if s.dirty {
+				s.saveLocked()
+			}
+			s.mu.Unlock()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// saveLocked 先写临时文件再重命名，避免写入中途退出导致文件损坏
+func (s *Store) saveLocked() error {
+	f := storeFile{Usage: s.usage}
+	for _, key := range s.keys {
+		f.Keys = append(f.Keys, key)
+	}
+	sort.Slice(f.Keys, func(i, j int) bool { return f.Keys[i].CreatedAt.Before(f.Keys[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("保存密钥文件失败: %w", err)
+	}
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("保存密钥文件失败: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("保存密钥文件失败: %w", err)
+	}
+	s.dirty = false
+	return nil
+}
+
+func hashKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成密钥失败: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func day(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// untilTomorrow 距离下一个UTC零点的时长
+func untilTomorrow(now time.Time) time.Duration {
+	now = now.UTC()
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	return tomorrow.Sub(now)
+}
@@ -0,0 +1,154 @@
+package apikey
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// errorResponse 与截图服务的错误响应格式一致
+type errorResponse struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg})
+}
+
+// FromRequest 从 X-API-Key 请求头或 api_key 查询参数读取密钥
+func FromRequest(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	return r.URL.Query().Get("api_key")
+}
+
+// contextKey 请求上下文中保存密钥信息的键
+type contextKey struct{}
+
+// FromContext 返回通过 Require 校验的密钥信息，未经过校验时返回nil
+func FromContext(ctx context.Context) *KeyInfo {
+	info, _ := ctx.Value(contextKey{}).(*KeyInfo)
+	return info
+}
+
+// Require 要求请求携带有效的API密钥，超过速率限制或配额时返回429和Retry-After
+func (s *Store) Require(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		secret := FromRequest(r)
+		if secret == "" {
+			writeError(w, http.StatusUnauthorized, "缺少API密钥，请通过 X-API-Key 请求头或 api_key 参数提供")
+			return
+		}
+
+		info, err := s.Authorize(secret)
+		var limitErr *LimitError
+		switch {
+		case errors.As(err, &limitErr):
+			retry := int(limitErr.RetryAfter.Seconds() + 0.999)
+			w.Header().Set("Retry-After", strconv.Itoa(max(retry, 1)))
+			writeError(w, http.StatusTooManyRequests, err.Error())
+			return
+		case err != nil:
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		if info.RateLimit > 0 {
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(info.RateLimit))
+		}
+		if info.DailyQuota > 0 {
+			w.Header().Set("X-Quota-Remaining", strconv.Itoa(info.DailyQuota-info.Usage.Today))
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, info)))
+	}
+}
+
+// createRequest 创建密钥的请求体
+type createRequest struct {
+	Name       string `json:"name"`
+	RateLimit  int    `json:"rate_limit"`
+	DailyQuota int    `json:"daily_quota"`
+}
+
+// createResponse 创建密钥的响应，密钥明文只返回这一次
+type createResponse struct {
+	*Key
+	Secret string `json:"key"`
+}
+
+// AdminHandler 返回密钥管理接口，请求需要携带 Authorization: Bearer <token>：
+//
+//	GET    /admin/keys       列出所有密钥和使用情况
+//	POST   /admin/keys       创建密钥
+//	GET    /admin/keys/{id}  查看密钥的使用情况
+//	DELETE /admin/keys/{id}  吊销密钥
+func AdminHandler(s *Store, token string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /admin/keys", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.List())
+	})
+
+	mux.HandleFunc("POST /admin/keys", func(w http.ResponseWriter, r *http.Request) {
+		var req createRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "无效的JSON请求体: "+err.Error())
+			return
+		}
+		if req.Name == "" || req.RateLimit < 0 || req.DailyQuota < 0 {
+			writeError(w, http.StatusBadRequest, "请提供name，rate_limit和daily_quota不能为负数")
+			return
+		}
+		key, secret, err := s.Create(req.Name, req.RateLimit, req.DailyQuota)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		created := *key
+		created.Hash = ""
+		writeJSON(w, http.StatusCreated, createResponse{Key: &created, Secret: secret})
+	})
+
+	mux.HandleFunc("GET /admin/keys/{id}", func(w http.ResponseWriter, r *http.Request) {
+		info, err := s.Get(r.PathValue("id"))
+		if err != nil {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, info)
+	})
+
+	mux.HandleFunc("DELETE /admin/keys/{id}", func(w http.ResponseWriter, r *http.Request) {
+		err := s.Revoke(r.PathValue("id"))
+		if errors.Is(err, ErrNotFound) {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "无效的管理令牌")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
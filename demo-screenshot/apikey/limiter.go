@@ -0,0 +1,33 @@
+package apikey
+
+import "time"
+
+// limiter 令牌桶，容量为每分钟的请求数，按速率均匀补充，允许短时间的突发请求
+type limiter struct {
+	limit  int     // 每分钟的请求数
+	tokens float64 // 当前可用的令牌数
+	last   time.Time
+}
+
+func newLimiter(perMinute int, now time.Time) *limiter {
+	return &limiter{
+		limit:  perMinute,
+		tokens: float64(perMinute),
+		last:   now,
+	}
+}
+
+// take 取走一个令牌，没有可用令牌时返回需要等待的时长
+func (l *limiter) take(now time.Time) time.Duration {
+	rate := float64(l.limit) / float64(time.Minute) // 每纳秒补充的令牌数
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens = min(l.tokens+float64(elapsed)*rate, float64(l.limit))
+		l.last = now
+	}
+
+	if l.tokens < 1 {
+		return time.Duration((1 - l.tokens) / rate)
+	}
+	l.tokens--
+	return 0
+}
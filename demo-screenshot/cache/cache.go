@@ -0,0 +1,285 @@
+// Package cache 截图结果的两级缓存（内存和磁盘），相同的并发请求共用一次截图
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry 一个缓存的截图结果
+type Entry struct {
+	Data        []byte    `json:"-"`
+	ContentType string    `json:"content_type"`
+	Extension   string    `json:"extension"` // 文件扩展名，不含点
+	ETag        string    `json:"etag"`
+	Created     time.Time `json:"created"`
+}
+
+// NewEntry 创建缓存项并计算ETag
+func NewEntry(data []byte, contentType, extension string) *Entry {
+	return &Entry{
+		Data:        data,
+		ContentType: contentType,
+		Extension:   extension,
+		ETag:        ETag(data),
+		Created:     time.Now(),
+	}
+}
+
+// Status 请求的缓存状态，用于 X-Cache 响应头
+type Status string
+
+const (
+	Hit    Status = "HIT"    // 命中缓存
+	Miss   Status = "MISS"   // 重新截图
+	Shared Status = "SHARED" // 与同时进行的相同请求共用一次截图
+)
+
+// Options 缓存的配置选项
+type Options struct {
+	TTL       time.Duration // 缓存有效期
+	MaxMemory int64         // 内存缓存的字节数上限
+	MaxDisk   int64         // 磁盘缓存的字节数上限
+	Dir       string        // 磁盘缓存目录，为空时只使用内存
+}
+
+// Cache 截图结果缓存，先查内存再查磁盘，磁盘命中后放回内存
+type Cache struct {
+	options Options
+
+	mu     sync.Mutex
+	memory *lru[*Entry]
+	disk   *lru[*Entry] // 只保存元数据，数据在文件中
+	calls  map[string]*call
+}
+
+// call 一次进行中的截图，相同键的请求等待它完成
+type call struct {
+	done  chan struct{}
+	entry *Entry
+	err   error
+}
+
+// New 创建缓存，指定了磁盘目录时加载目录中未过期的缓存
+func New(options Options) (*Cache, error) {
+	c := &Cache{
+		options: options,
+		memory:  newLRU[*Entry](options.MaxMemory, nil),
+		calls:   make(map[string]*call),
+	}
+	if options.Dir == "" {
+		return c, nil
+	}
+
+	if err := os.MkdirAll(options.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("创建缓存目录失败: %w", err)
+	}
+	c.disk = newLRU(options.MaxDisk, func(key string, _ *Entry) {
+		os.Remove(c.dataPath(key))
+		os.Remove(c.metaPath(key))
+	})
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// load 按最近访问时间加载磁盘缓存，删除过期和损坏的缓存
+func (c *Cache) load() error {
+	files, err := filepath.Glob(filepath.Join(c.options.Dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("读取缓存目录失败: %w", err)
+	}
+
+	type diskItem struct {
+		key      string
+		entry    *Entry
+		size     int64
+		accessed time.Time
+	}
+	var items []diskItem
+	for _, file := range files {
+		key := strings.TrimSuffix(filepath.Base(file), ".json")
+		entry := &Entry{}
+		data, err := os.ReadFile(file)
+		if err == nil {
+			err = json.Unmarshal(data, entry)
+		}
+		info, statErr := os.Stat(c.dataPath(key))
+		if err != nil || statErr != nil || c.expired(entry) {
+			os.Remove(c.dataPath(key))
+			os.Remove(file)
+			continue
+		}
+		items = append(items, diskItem{key, entry, info.Size(), info.ModTime()})
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].accessed.After(items[j].accessed) })
+	for _, item := range items {
+		c.disk.pushBack(item.key, item.entry, item.size)
+	}
+	for c.disk.bytes > c.disk.maxBytes {
+		c.disk.evict(c.disk.order.Back())
+	}
+	return nil
+}
+
+// Get 返回未过期的缓存
+func (c *Cache) Get(key string) (*Entry, bool) {
+	c.mu.Lock()
+	if entry, ok := c.memory.get(key); ok {
+		if !c.expired(entry) {
+			c.mu.Unlock()
+			return entry, true
+		}
+		c.memory.remove(key)
+	}
+	if c.disk == nil {
+		c.mu.Unlock()
+		return nil, false
+	}
+	meta, ok := c.disk.get(key)
+	if ok && c.expired(meta) {
+		c.disk.remove(key)
+		ok = false
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(c.dataPath(key))
+	if err != nil {
+		c.mu.Lock()
+		c.disk.remove(key)
+		c.mu.Unlock()
+		return nil, false
+	}
+	// 修改时间作为最近访问时间，重启后按此恢复淘汰顺序
+	now := time.Now()
+	os.Chtimes(c.dataPath(key), now, now)
+
+	entry := *meta
+	entry.Data = data
+	c.mu.Lock()
+	c.memory.add(key, &entry, int64(len(data)))
+	c.mu.Unlock()
+	return &entry, true
+}
+
+// Set 保存缓存，同时写入内存和磁盘
+func (c *Cache) Set(key string, entry *Entry) error {
+	size := int64(len(entry.Data))
+	c.mu.Lock()
+	c.memory.add(key, entry, size)
+	c.mu.Unlock()
+
+	if c.disk == nil || size > c.options.MaxDisk {
+		return nil
+	}
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(c.dataPath(key), entry.Data); err != nil {
+		return fmt.Errorf("写入缓存失败: %w", err)
+	}
+	if err := writeFile(c.metaPath(key), meta); err != nil {
+		return fmt.Errorf("写入缓存失败: %w", err)
+	}
+
+	// 磁盘索引只保存元数据
+	metaEntry := *entry
+	metaEntry.Data = nil
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// 旧的缓存文件已被覆盖，不能再由淘汰回调删除
+	c.disk.forget(key)
+	c.disk.add(key, &metaEntry, size)
+	return nil
+}
+
+// Do 返回缓存的结果，没有缓存或 fresh 为 true 时调用 fn 截图并保存结果。
+// 相同键的请求同时到达时只调用一次 fn，其他请求等待并共用结果
+func (c *Cache) Do(key string, fresh bool, fn func() (*Entry, error)) (*Entry, Status, error) {
+	if !fresh {
+		if entry, ok := c.Get(key); ok {
+			return entry, Hit, nil
+		}
+	}
+
+	c.mu.Lock()
+	if cl, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		<-cl.done
+		return cl.entry, Shared, cl.err
+	}
+	cl := &call{done: make(chan struct{})}
+	c.calls[key] = cl
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, key)
+		c.mu.Unlock()
+		close(cl.done)
+	}()
+
+	cl.entry, cl.err = fn()
+	if cl.err == nil && cl.entry == nil {
+		cl.err = errors.New("截图结果为空")
+	}
+	if cl.err != nil {
+		return nil, Miss, cl.err
+	}
+	c.Set(key, cl.entry)
+	return cl.entry, Miss, nil
+}
+
+func (c *Cache) expired(entry *Entry) bool {
+	return time.Since(entry.Created) > c.options.TTL
+}
+
+func (c *Cache) dataPath(key string) string {
+	return filepath.Join(c.options.Dir, key+".data")
+}
+
+func (c *Cache) metaPath(key string) string {
+	return filepath.Join(c.options.Dir, key+".json")
+}
+
+// writeFile 先写临时文件再重命名，避免读到写了一半的文件
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ETag 根据内容生成强ETag
+func ETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// MatchETag 判断 If-None-Match 请求头是否匹配ETag，支持多个值、弱ETag和 *
+func MatchETag(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
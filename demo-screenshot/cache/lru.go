@@ -0,0 +1,81 @@
+package cache
+
+import "container/list"
+
+// lru 按字节数限制容量的LRU列表，超出容量时从最久未使用的一端淘汰
+type lru[V any] struct {
+	maxBytes int64
+	bytes    int64
+	order    *list.List // 队首为最近使用
+	items    map[string]*list.Element
+	onEvict  func(key string, value V)
+}
+
+type lruItem[V any] struct {
+	key   string
+	value V
+	size  int64
+}
+
+func newLRU[V any](maxBytes int64, onEvict func(key string, value V)) *lru[V] {
+	return &lru[V]{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+		onEvict:  onEvict,
+	}
+}
+
+// get 返回缓存项并标记为最近使用
+func (l *lru[V]) get(key string) (V, bool) {
+	el, ok := l.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	l.order.MoveToFront(el)
+	return el.Value.(*lruItem[V]).value, true
+}
+
+// add 添加或替换缓存项，超过容量上限的单个缓存项不保存
+func (l *lru[V]) add(key string, value V, size int64) bool {
+	l.remove(key)
+	if size > l.maxBytes {
+		return false
+	}
+	l.items[key] = l.order.PushFront(&lruItem[V]{key: key, value: value, size: size})
+	l.bytes += size
+	for l.bytes > l.maxBytes {
+		l.evict(l.order.Back())
+	}
+	return true
+}
+
+// pushBack 添加一个最久未使用的缓存项，用于按访问时间顺序加载磁盘缓存
+func (l *lru[V]) pushBack(key string, value V, size int64) {
+	l.items[key] = l.order.PushBack(&lruItem[V]{key: key, value: value, size: size})
+	l.bytes += size
+}
+
+func (l *lru[V]) remove(key string) {
+	if el, ok := l.items[key]; ok {
+		l.evict(el)
+	}
+}
+
+// forget 移除缓存项但不调用淘汰回调
+func (l *lru[V]) forget(key string) {
+	if el, ok := l.items[key]; ok {
+		item := l.order.Remove(el).(*lruItem[V])
+		delete(l.items, key)
+		l.bytes -= item.size
+	}
+}
+
+func (l *lru[V]) evict(el *list.Element) {
+	item := el.Value.(*lruItem[V])
+	l.forget(item.key)
+	if l.onEvict != nil {
+		l.onEvict(item.key, item.value)
+	}
+}
@@ -0,0 +1,303 @@
+// Package diff 比较两张截图，用于发现界面的视觉回归。
+// 颜色差异按YIQ色彩空间计算，并识别抗锯齿像素，算法参考 pixelmatch
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg" // 注册JPEG解码器
+	"image/png"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Options 比较选项
+type Options struct {
+	Threshold float64           // 颜色差异的阈值(0-1)，越小越敏感，0表示颜色不同即为差异
+	IncludeAA bool              // 把抗锯齿像素也算作差异
+	Ignore    []image.Rectangle // 不参与比较的区域（图片像素）
+	Alpha     float64           // 差异图中未变化像素的不透明度(0-1)，0表示只显示差异
+}
+
+// 解码图片的尺寸上限，避免上传的图片占用过多内存
+const (
+	MaxSide   = 16384    // 单边的最大像素数，与全页面截图的最大高度一致
+	MaxPixels = 32 << 20 // 总像素数上限，约为 2048×16384
+)
+
+// DefaultOptions 返回默认的比较选项，阈值和不透明度为0.1
+func DefaultOptions() Options {
+	return Options{Threshold: 0.1, Alpha: 0.1}
+}
+
+// Result 比较结果
+type Result struct {
+	Image           *image.NRGBA    // 差异图：红色为差异，黄色为抗锯齿，蓝色为忽略区域
+	TotalPixels     int             // 参与比较的像素数，不包括忽略区域
+	ChangedPixels   int             // 颜色值不完全相同的像素数
+	DiffPixels      int             // 超过阈值的差异像素数，不包括抗锯齿像素
+	AAPixels        int             // 只是抗锯齿不同的像素数
+	MismatchPercent float64         // DiffPixels 占 TotalPixels 的百分比
+	Bounds          image.Rectangle // 包含所有差异像素的最小矩形
+	SizeMismatch    bool            // 两张图片的尺寸不同，超出部分计为差异
+}
+
+var (
+	diffColor   = color.NRGBA{R: 255, A: 255}
+	aaColor     = color.NRGBA{R: 255, G: 255, A: 255}
+	ignoreColor = color.NRGBA{R: 180, G: 210, B: 255, A: 255}
+)
+
+// Compare 逐像素比较两张图片，尺寸不同时比较两者的最大范围，只有一张图片覆盖的像素计为差异
+func Compare(a, b image.Image, options Options) *Result {
+	options.Threshold = min(max(options.Threshold, 0), 1)
+	options.Alpha = min(max(options.Alpha, 0), 1)
+
+	imgA, imgB := toNRGBA(a), toNRGBA(b)
+	wa, ha := imgA.Rect.Dx(), imgA.Rect.Dy()
+	wb, hb := imgB.Rect.Dx(), imgB.Rect.Dy()
+	width, height := max(wa, wb), max(ha, hb)
+
+	result := &Result{
+		Image:        image.NewNRGBA(image.Rect(0, 0, width, height)),
+		SizeMismatch: wa != wb || ha != hb,
+	}
+	// 35215 为YIQ色彩空间中两种颜色的最大差值
+	maxDelta := 35215 * options.Threshold * options.Threshold
+	common := image.Rect(0, 0, min(wa, wb), min(ha, hb))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			p := image.Pt(x, y)
+			if ignored(p, options.Ignore) {
+				result.Image.SetNRGBA(x, y, ignoreColor)
+				continue
+			}
+			result.TotalPixels++
+
+			if !p.In(common) {
+				result.ChangedPixels++
+				result.addDiff(p)
+				continue
+			}
+
+			if !samePixel(imgA, imgB, p, p) {
+				result.ChangedPixels++
+			}
+			delta := colorDelta(imgA, imgB, p, p, false)
+			switch {
+			case math.Abs(delta) <= maxDelta:
+				result.Image.SetNRGBA(x, y, faded(imgA, p, options.Alpha))
+			case !options.IncludeAA && (antialiased(imgA, imgB, p) || antialiased(imgB, imgA, p)):
+				result.AAPixels++
+				result.Image.SetNRGBA(x, y, aaColor)
+			default:
+				result.addDiff(p)
+			}
+		}
+	}
+
+	if result.TotalPixels > 0 {
+		result.MismatchPercent = float64(result.DiffPixels) / float64(result.TotalPixels) * 100
+	}
+	return result
+}
+
+func (r *Result) addDiff(p image.Point) {
+	r.DiffPixels++
+	r.Image.SetNRGBA(p.X, p.Y, diffColor)
+	r.Bounds = r.Bounds.Union(image.Rectangle{Min: p, Max: p.Add(image.Pt(1, 1))})
+}
+
+func ignored(p image.Point, regions []image.Rectangle) bool {
+	for _, r := range regions {
+		if p.In(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// toNRGBA 转换为坐标从(0,0)开始的 NRGBA 图片
+func toNRGBA(img image.Image) *image.NRGBA {
+	if n, ok := img.(*image.NRGBA); ok && n.Rect.Min == (image.Point{}) {
+		return n
+	}
+	b := img.Bounds()
+	n := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(n, n.Rect, img, b.Min, draw.Src)
+	return n
+}
+
+func samePixel(a, b *image.NRGBA, pa, pb image.Point) bool {
+	i, j := a.PixOffset(pa.X, pa.Y), b.PixOffset(pb.X, pb.Y)
+	return bytes.Equal(a.Pix[i:i+4], b.Pix[j:j+4])
+}
+
+// colorDelta 计算两个像素在YIQ色彩空间中的差异，半透明像素先与白色背景混合。
+// yOnly 为 true 时只比较亮度；结果的符号表示哪个像素更亮
+func colorDelta(a, b *image.NRGBA, pa, pb image.Point, yOnly bool) float64 {
+	if samePixel(a, b, pa, pb) {
+		return 0
+	}
+	r1, g1, b1 := blended(a, pa)
+	r2, g2, b2 := blended(b, pb)
+
+	y1, y2 := rgb2y(r1, g1, b1), rgb2y(r2, g2, b2)
+	y := y1 - y2
+	if yOnly {
+		return y
+	}
+	i := rgb2i(r1, g1, b1) - rgb2i(r2, g2, b2)
+	q := rgb2q(r1, g1, b1) - rgb2q(r2, g2, b2)
+	delta := 0.5053*y*y + 0.299*i*i + 0.1957*q*q
+	if y1 > y2 {
+		return -delta
+	}
+	return delta
+}
+
+// blended 返回与白色背景混合后的颜色
+func blended(img *image.NRGBA, p image.Point) (r, g, b float64) {
+	c := img.NRGBAAt(p.X, p.Y)
+	r, g, b = float64(c.R), float64(c.G), float64(c.B)
+	if c.A < 255 {
+		a := float64(c.A) / 255
+		r, g, b = 255+(r-255)*a, 255+(g-255)*a, 255+(b-255)*a
+	}
+	return r, g, b
+}
+
+func rgb2y(r, g, b float64) float64 { return r*0.29889531 + g*0.58662247 + b*0.11448223 }
+func rgb2i(r, g, b float64) float64 { return r*0.59597799 - g*0.27417610 - b*0.32180189 }
+func rgb2q(r, g, b float64) float64 { return r*0.21147017 - g*0.52261711 + b*0.31114694 }
+
+// faded 未变化的像素在差异图中显示为淡化的灰度
+func faded(img *image.NRGBA, p image.Point, alpha float64) color.NRGBA {
+	r, g, b := blended(img, p)
+	v := uint8(255 + (rgb2y(r, g, b)-255)*alpha)
+	return color.NRGBA{R: v, G: v, B: v, A: 255}
+}
+
+// antialiased 判断像素是否为抗锯齿像素：周围的像素中同时存在更亮和更暗的像素，
+// 且最亮或最暗的那个像素在两张图片中都处于颜色相同的区域
+func antialiased(img, other *image.NRGBA, p image.Point) bool {
+	bounds := img.Rect.Intersect(other.Rect)
+	x0, y0 := max(p.X-1, bounds.Min.X), max(p.Y-1, bounds.Min.Y)
+	x2, y2 := min(p.X+1, bounds.Max.X-1), min(p.Y+1, bounds.Max.Y-1)
+
+	zeroes := 0
+	if p.X == x0 || p.X == x2 || p.Y == y0 || p.Y == y2 {
+		zeroes = 1
+	}
+	var minDelta, maxDelta float64
+	var darkest, brightest image.Point
+	for x := x0; x <= x2; x++ {
+		for y := y0; y <= y2; y++ {
+			if x == p.X && y == p.Y {
+				continue
+			}
+			q := image.Pt(x, y)
+			delta := colorDelta(img, img, p, q, true)
+			switch {
+			case delta == 0:
+				zeroes++
+				if zeroes > 2 {
+					return false
+				}
+			case delta < minDelta:
+				minDelta, darkest = delta, q
+			case delta > maxDelta:
+				maxDelta, brightest = delta, q
+			}
+		}
+	}
+	if minDelta == 0 || maxDelta == 0 {
+		return false
+	}
+	return (hasManySiblings(img, darkest, bounds) && hasManySiblings(other, darkest, bounds)) ||
+		(hasManySiblings(img, brightest, bounds) && hasManySiblings(other, brightest, bounds))
+}
+
+// hasManySiblings 判断像素周围是否有3个以上颜色完全相同的像素
+func hasManySiblings(img *image.NRGBA, p image.Point, bounds image.Rectangle) bool {
+	x0, y0 := max(p.X-1, bounds.Min.X), max(p.Y-1, bounds.Min.Y)
+	x2, y2 := min(p.X+1, bounds.Max.X-1), min(p.Y+1, bounds.Max.Y-1)
+
+	zeroes := 0
+	if p.X == x0 || p.X == x2 || p.Y == y0 || p.Y == y2 {
+		zeroes = 1
+	}
+	for x := x0; x <= x2; x++ {
+		for y := y0; y <= y2; y++ {
+			if x == p.X && y == p.Y {
+				continue
+			}
+			if samePixel(img, img, p, image.Pt(x, y)) {
+				zeroes++
+			}
+			if zeroes > 2 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Decode 解码PNG或JPEG图片，尺寸超过 MaxSide 或 MaxPixels 时返回错误
+func Decode(data []byte) (image.Image, error) {
+	// 先读取尺寸，拒绝解码后过大的图片
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("无法解码图片（只支持PNG和JPEG）: %w", err)
+	}
+	if config.Width > MaxSide || config.Height > MaxSide || config.Width*config.Height > MaxPixels {
+		return nil, fmt.Errorf("图片尺寸 %d×%d 过大，单边不能超过 %d 像素，总像素数不能超过 %d", config.Width, config.Height, MaxSide, MaxPixels)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("无法解码图片（只支持PNG和JPEG）: %w", err)
+	}
+	return img, nil
+}
+
+// EncodePNG 把差异图编码为PNG
+func EncodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("生成差异图失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ParseRect 解析 "x,y,宽,高" 形式的矩形区域
+func ParseRect(value string) (image.Rectangle, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return image.Rectangle{}, fmt.Errorf("无效的区域: %s", value)
+	}
+	var nums [4]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return image.Rectangle{}, fmt.Errorf("无效的区域: %s", value)
+		}
+		nums[i] = n
+	}
+	if nums[2] <= 0 || nums[3] <= 0 {
+		return image.Rectangle{}, fmt.Errorf("区域的宽高必须大于0: %s", value)
+	}
+	return image.Rect(nums[0], nums[1], nums[0]+nums[2], nums[1]+nums[3]), nil
+}
+
+// Rect 把浮点坐标的区域向外取整为像素区域
+func Rect(x, y, width, height float64) image.Rectangle {
+	return image.Rect(
+		int(math.Floor(x)), int(math.Floor(y)),
+		int(math.Ceil(x+width)), int(math.Ceil(y+height)),
+	)
+}
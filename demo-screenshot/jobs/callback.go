@@ -0,0 +1,120 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// CallbackResult 回调的发送情况
+type CallbackResult struct {
+	Delivered bool   `json:"delivered"`
+	Attempts  int    `json:"attempts"`
+	Error     string `json:"error,omitempty"`
+}
+
+// 单次回调请求的超时时间
+const callbackTimeout = 10 * time.Second
+
+// 回调失败后的重试间隔，重试次数为 len(callbackBackoff)
+var callbackBackoff = []time.Duration{5 * time.Second, 30 * time.Second, 2 * time.Minute}
+
+// notify 任务结束后向回调URL发送任务状态，失败时按 callbackBackoff 重试
+func (q *Queue) notify(id string) {
+	defer q.wg.Done()
+
+	result := &CallbackResult{}
+	for {
+		q.mu.Lock()
+		rec, ok := q.jobs[id]
+		var job Job
+		if ok {
+			job = rec.Job
+		}
+		q.mu.Unlock()
+		if !ok {
+			return
+		}
+
+		result.Attempts++
+		err := q.deliver(job)
+		if err == nil {
+			result.Delivered, result.Error = true, ""
+			break
+		}
+		result.Error = err.Error()
+		if result.Attempts > len(callbackBackoff) {
+			break
+		}
+
+		select {
+		case <-time.After(callbackBackoff[result.Attempts-1]):
+		case <-q.ctx.Done():
+			// 队列关闭时放弃重试，下次启动时重新发送
+			return
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if rec, ok := q.jobs[id]; ok {
+		rec.Callback = result
+		q.saveLocked(rec)
+	}
+}
+
+// deliver 以JSON格式POST任务状态到回调URL，2xx响应视为成功。
+// 设置了 CallbackSecret 时，X-Signature-256 请求头为 sha256=<请求体的HMAC-SHA256十六进制值>
+func (q *Queue) deliver(job Job) error {
+	ctx, cancel := context.WithTimeout(q.ctx, callbackTimeout)
+	defer cancel()
+
+	if err := q.checkCallback(ctx, job.CallbackURL); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建回调请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Job-ID", job.ID)
+	if q.options.CallbackSecret != "" {
+		mac := hmac.New(sha256.New, []byte(q.options.CallbackSecret))
+		mac.Write(body)
+		req.Header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送回调失败: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("回调返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (q *Queue) checkCallback(ctx context.Context, url string) error {
+	if q.options.CheckCallback == nil {
+		return nil
+	}
+	if err := q.options.CheckCallback(ctx, url); err != nil {
+		return fmt.Errorf("回调URL不允许访问: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,443 @@
+// Package jobs 异步执行截图任务的队列，任务保存在磁盘上，服务重启后未完成的任务会继续执行
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound 任务不存在或已过期删除
+var ErrNotFound = errors.New("任务不存在")
+
+// ErrQueueFull 等待执行的任务数已达上限
+var ErrQueueFull = errors.New("任务队列已满，请稍后重试")
+
+// ErrNotReady 任务尚未成功完成，没有可下载的结果
+var ErrNotReady = errors.New("任务尚未完成")
+
+// Status 任务状态
+type Status string
+
+const (
+	StatusQueued  Status = "queued"  // 等待执行
+	StatusRunning Status = "running" // 正在执行
+	StatusDone    Status = "done"    // 已完成，可以下载结果
+	StatusFailed  Status = "failed"  // 执行失败
+)
+
+// Job 任务的状态，GET /jobs/{id} 和回调都返回此结构
+type Job struct {
+	ID          string          `json:"id"`
+	Status      Status          `json:"status"`
+	URL         string          `json:"url"`
+	CallbackURL string          `json:"callback_url,omitempty"`
+	Owner       string          `json:"owner,omitempty"` // 提交任务的API密钥ID
+	ContentType string          `json:"content_type,omitempty"`
+	Size        int             `json:"size,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	Callback    *CallbackResult `json:"callback,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	StartedAt   *time.Time      `json:"started_at,omitempty"`
+	FinishedAt  *time.Time      `json:"finished_at,omitempty"`
+}
+
+// Request 任务的截图请求，原样保存查询参数和请求体，执行时再解析
+type Request struct {
+	Query string          `json:"query"`
+	Body  json.RawMessage `json:"body,omitempty"`
+}
+
+// Result 任务的执行结果
+type Result struct {
+	Data        []byte
+	ContentType string
+	Extension   string // 结果文件的扩展名，不含点
+}
+
+// RunFunc 执行一个截图任务，队列关闭时 ctx 被取消，此时返回包装了 context.Canceled 的错误的任务会重新排队
+type RunFunc func(ctx context.Context, req Request) (*Result, error)
+
+// Options 队列的配置选项
+type Options struct {
+	Workers    int           // 同时执行的任务数
+	MaxPending int           // 等待执行的任务数上限，0表示不限制
+	Retention  time.Duration // 已结束的任务保留多久，0表示一直保留
+	Run        RunFunc
+
+	// CheckCallback 在发送回调前检查回调URL，为空时不检查
+	CheckCallback func(ctx context.Context, url string) error
+	// DialCallback 建立回调连接，为空时使用默认拨号。用于在连接时再次检查解析出的地址，防止DNS重绑定
+	DialCallback func(ctx context.Context, network, address string) (net.Conn, error)
+	// CallbackSecret 不为空时用 HMAC-SHA256 签名回调请求体
+	CallbackSecret string
+}
+
+// record 保存在磁盘上的任务，包括不对外返回的请求内容
+type record struct {
+	Job
+	Request Request `json:"request"`
+	File    string  `json:"file,omitempty"` // 结果文件名
+}
+
+// Queue 截图任务队列，每个任务保存为目录中的一个JSON文件，结果保存在同一目录
+type Queue struct {
+	dir     string
+	options Options
+	client  *http.Client
+
+	mu      sync.Mutex
+	jobs    map[string]*record
+	pending []*record // 按提交顺序等待执行的任务
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wake   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// 清理过期任务的间隔
+const cleanupInterval = time.Minute
+
+// Open 打开任务目录，加载之前保存的任务并启动工作协程。
+// 未完成的任务（包括重启前正在执行的任务）会重新排队执行
+func Open(dir string, options Options) (*Queue, error) {
+	if options.Run == nil {
+		return nil, errors.New("没有指定任务的执行函数")
+	}
+	if options.Workers <= 0 {
+		options.Workers = 1
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("创建任务目录失败: %w", err)
+	}
+
+	q := &Queue{
+		dir:     dir,
+		options: options,
+		jobs:    make(map[string]*record),
+		wake:    make(chan struct{}, 1),
+	}
+	q.ctx, q.cancel = context.WithCancel(context.Background())
+	q.client = &http.Client{
+		Timeout: callbackTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("重定向次数过多")
+			}
+			return q.checkCallback(req.Context(), req.URL.String())
+		},
+	}
+	if options.DialCallback != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = nil
+		transport.DialContext = options.DialCallback
+		q.client.Transport = transport
+	}
+
+	notify, err := q.load()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < options.Workers; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+	if options.Retention > 0 {
+		q.wg.Add(1)
+		go q.cleanupLoop()
+	}
+	// 重启前没来得及发送的回调
+	for _, id := range notify {
+		q.wg.Add(1)
+		go q.notify(id)
+	}
+	q.signal()
+	return q, nil
+}
+
+// load 读取目录中的任务，返回需要补发回调的任务ID
+func (q *Queue) load() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(q.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("读取任务目录失败: %w", err)
+	}
+
+	var notify []string
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("读取任务失败: %w", err)
+		}
+		rec := &record{}
+		if err := json.Unmarshal(data, rec); err != nil {
+			return nil, fmt.Errorf("解析任务 %s 失败: %w", filepath.Base(file), err)
+		}
+		q.jobs[rec.ID] = rec
+
+		switch rec.Status {
+		case StatusQueued, StatusRunning:
+			rec.Status, rec.StartedAt = StatusQueued, nil
+			q.pending = append(q.pending, rec)
+		default:
+			if rec.CallbackURL != "" && rec.Callback == nil {
+				notify = append(notify, rec.ID)
+			}
+		}
+	}
+	sort.Slice(q.pending, func(i, j int) bool { return q.pending[i].CreatedAt.Before(q.pending[j].CreatedAt) })
+	return notify, nil
+}
+
+// Enqueue 提交任务，返回任务的初始状态
+func (q *Queue) Enqueue(pageURL, callbackURL, owner string, req Request) (*Job, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	rec := &record{
+		Job: Job{
+			ID:          id,
+			Status:      StatusQueued,
+			URL:         pageURL,
+			CallbackURL: callbackURL,
+			Owner:       owner,
+			CreatedAt:   time.Now().UTC(),
+		},
+		Request: req,
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.ctx.Err() != nil {
+		return nil, errors.New("任务队列已关闭")
+	}
+	if q.options.MaxPending > 0 && len(q.pending) >= q.options.MaxPending {
+		return nil, ErrQueueFull
+	}
+	if err := q.saveLocked(rec); err != nil {
+		return nil, err
+	}
+	q.jobs[id] = rec
+	q.pending = append(q.pending, rec)
+	q.signal()
+
+	job := rec.Job
+	return &job, nil
+}
+
+// Get 返回任务的当前状态
+func (q *Queue) Get(id string) (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	rec, ok := q.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	job := rec.Job
+	return &job, nil
+}
+
+// OpenResult 打开已完成任务的结果文件，调用方负责关闭
+func (q *Queue) OpenResult(id string) (*os.File, *Job, error) {
+	q.mu.Lock()
+	rec, ok := q.jobs[id]
+	var job Job
+	var file string
+	if ok {
+		job, file = rec.Job, rec.File
+	}
+	q.mu.Unlock()
+
+	if !ok {
+		return nil, nil, ErrNotFound
+	}
+	if job.Status != StatusDone {
+		return nil, &job, ErrNotReady
+	}
+	f, err := os.Open(filepath.Join(q.dir, file))
+	if err != nil {
+		return nil, &job, fmt.Errorf("打开任务结果失败: %w", err)
+	}
+	return f, &job, nil
+}
+
+// Pending 返回等待执行的任务数
+func (q *Queue) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// Close 停止接收任务并等待正在执行的任务结束。
+// 被中断的任务保持排队状态，下次启动时重新执行
+func (q *Queue) Close() {
+	q.mu.Lock()
+	q.cancel()
+	q.mu.Unlock()
+	q.wg.Wait()
+}
+
+// signal 唤醒一个空闲的工作协程
+func (q *Queue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) worker() {
+	defer q.wg.Done()
+	for {
+		if rec := q.next(); rec != nil {
+			q.run(rec)
+			continue
+		}
+		select {
+		case <-q.wake:
+		case <-q.ctx.Done():
+			return
+		}
+	}
+}
+
+// next 取出下一个等待执行的任务并标记为执行中
+func (q *Queue) next() *record {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.ctx.Err() != nil || len(q.pending) == 0 {
+		return nil
+	}
+	rec := q.pending[0]
+	q.pending = q.pending[1:]
+	now := time.Now().UTC()
+	rec.Status, rec.StartedAt = StatusRunning, &now
+	q.saveLocked(rec)
+
+	// 还有任务时继续唤醒其他工作协程
+	if len(q.pending) > 0 {
+		q.signal()
+	}
+	return rec
+}
+
+// run 执行任务并保存结果，完成后发送回调
+func (q *Queue) run(rec *record) {
+	result, err := q.options.Run(q.ctx, rec.Request)
+	var file string
+	if err == nil {
+		file = rec.ID + "." + strings.TrimPrefix(result.Extension, ".")
+		err = writeFile(filepath.Join(q.dir, file), result.Data)
+	}
+
+	q.mu.Lock()
+	if errors.Is(err, context.Canceled) && q.ctx.Err() != nil {
+		// 队列关闭导致的中断，保持排队状态等待重启后执行
+		rec.Status, rec.StartedAt = StatusQueued, nil
+		q.saveLocked(rec)
+		q.mu.Unlock()
+		return
+	}
+
+	// 请求中可能有请求头、Cookie和密码，任务结束后不再保存
+	now := time.Now().UTC()
+	rec.FinishedAt, rec.Request = &now, Request{}
+	if err != nil {
+		rec.Status, rec.Error = StatusFailed, err.Error()
+	} else {
+		rec.Status, rec.File = StatusDone, file
+		rec.ContentType, rec.Size = result.ContentType, len(result.Data)
+	}
+	q.saveLocked(rec)
+	callback := rec.CallbackURL != ""
+	q.mu.Unlock()
+
+	if callback {
+		q.wg.Add(1)
+		go q.notify(rec.ID)
+	}
+}
+
+// cleanupLoop 定期删除过期的已结束任务和结果文件
+func (q *Queue) cleanupLoop() {
+	defer q.wg.Done()
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			q.cleanup(time.Now())
+		case <-q.ctx.Done():
+			return
+		}
+	}
+}
+
+func (q *Queue) cleanup(now time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for id, rec := range q.jobs {
+		if rec.FinishedAt == nil || now.Sub(*rec.FinishedAt) < q.options.Retention {
+			continue
+		}
+		// 回调还在重试时暂不删除
+		if rec.CallbackURL != "" && rec.Callback == nil {
+			continue
+		}
+		if rec.File != "" {
+			os.Remove(filepath.Join(q.dir, rec.File))
+		}
+		os.Remove(q.recordPath(id))
+		delete(q.jobs, id)
+	}
+}
+
+func (q *Queue) recordPath(id string) string {
+	return filepath.Join(q.dir, id+".json")
+}
+
+// saveLocked 保存任务状态
+func (q *Queue) saveLocked(rec *record) error {
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(q.recordPath(rec.ID), data); err != nil {
+		return fmt.Errorf("保存任务失败: %w", err)
+	}
+	return nil
+}
+
+// writeFile 先写临时文件再重命名，避免写入中途退出导致文件损坏。
+// 任务中可能包含Cookie等认证信息，只允许当前用户读写
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成任务ID失败: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -1,38 +1,146 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"path"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/fuwenhao/go-base/demo-screenshot/diff"
 	"github.com/fuwenhao/go-base/demo-screenshot/screenshot"
+	"github.com/fuwenhao/go-base/demo-screenshot/storage"
 )
 
 func main() {
+	// 比较两张已有的图片
+	if len(os.Args) >= 2 && os.Args[1] == "compare" {
+		compareFiles(os.Args[2:])
+		return
+	}
+
 	if len(os.Args) < 2 {
 		fmt.Println("使用方法: go run main.go <URL> [输出文件名.png] [选项]")
+		fmt.Println("      go run main.go compare <图片> <基准图片> [比较选项]")
 		fmt.Println("选项:")
 		fmt.Println("  --width=数值     : 设置截图宽度")
 		fmt.Println("  --height=数值    : 设置截图高度")
 		fmt.Println("  --full=true/false: 是否全页面截图")
-		fmt.Println("  --mobile=true/false: 是否使用移动设备模式")
+		fmt.Println("  --max-height=数值: 全页面截图的最大高度(CSS像素)，默认16384")
+		fmt.Println("  --scroll=true/false: 截图前分步滚动页面触发懒加载，同时开启全页面截图")
+		fmt.Println("  --scroll-step=数值: 每次滚动的距离(CSS像素)，默认为视口高度")
+		fmt.Println("  --scroll-delay=毫秒: 每次滚动后的等待时间(默认200)")
+		fmt.Println("  --scroll-max=数值: 最多滚动的次数(默认50)")
+		fmt.Println("  --hide-fixed=true/false: 固定和粘性定位的元素(如页头)只在第一屏显示")
+		fmt.Println("  --mobile=true/false: 是否使用移动设备模式（等同于 --device=" + screenshot.DefaultMobileDevice + "）")
+		fmt.Println("  --device=设备名称: 设备仿真预设，可用: " + strings.Join(screenshot.DeviceNames(), ", "))
+		fmt.Println("  --orientation=portrait/landscape: 设备方向")
+		fmt.Println("  --dpr=数值       : 设备像素比，如2表示Retina截图")
+		fmt.Println("  --color-scheme=light/dark: 模拟浅色或深色模式(prefers-color-scheme)")
+		fmt.Println("  --reduced-motion=true/false: 模拟减少动态效果(prefers-reduced-motion)")
+		fmt.Println("  --media=screen/print: 模拟CSS媒体类型")
+		fmt.Println("  --locale=语言    : 页面语言和Accept-Language，如 zh-CN、en-US")
+		fmt.Println("  --timezone=时区  : 时区，如 Asia/Shanghai、America/New_York")
+		fmt.Println("  --geolocation=纬度,经度[,精度]: 模拟地理位置并授予页面读取权限")
+		fmt.Println("  --disable-animations=true/false: 让CSS动画和过渡立即结束")
 		fmt.Println("  --wait=数值      : 等待时间(秒)")
 		fmt.Println("  --block-images=true/false: 是否屏蔽图片加载")
 		fmt.Println("  --block-js=true/false: 是否屏蔽JavaScript")
+		fmt.Println("  --block=类型列表 : 按资源类型屏蔽请求，逗号分隔: image,script,font,media,stylesheet")
+		fmt.Println("  --blocklist=文件 : EasyList或hosts格式的广告/跟踪器域名屏蔽列表")
+		fmt.Println("  --header=\"名称: 值\": 附加到截图URL所在的源的请求头，可重复指定")
+		fmt.Println("  --cookie=名称=值 : 导航前写入的Cookie，可重复指定")
+		fmt.Println("  --basic-auth=用户名:密码: HTTP基本认证")
+		fmt.Println("  --local-storage=键=值: 导航前写入的localStorage，可重复指定")
+		fmt.Println("  --session-storage=键=值: 导航前写入的sessionStorage，可重复指定")
+		fmt.Println("  --disable-web-security=true/false: 关闭浏览器的同源策略(不安全)")
 		fmt.Println("  --selector=CSS选择器: 等待指定元素出现后截图")
+		fmt.Println("  --steps=文件     : 截图前执行的交互步骤(JSON)，步骤中的截图保存为 输出文件名-步骤名")
+		fmt.Println("  --wait-until=事件列表: 等待的页面事件，逗号分隔: domcontentloaded,load,networkidle,fonts")
+		fmt.Println("  --wait-for=JS表达式: 等待表达式的值为真后截图")
+		fmt.Println("  --network-idle=毫秒: 无网络请求持续多久视为网络空闲(默认500)")
+		fmt.Println("  --wait-deadline=数值: 等待的总时长上限(秒)，超时后直接截图")
+		fmt.Println("  --css=CSS或@文件 : 页面加载后注入的CSS")
+		fmt.Println("  --script=JS或@文件: 页面加载后执行的JavaScript")
+		fmt.Println("  --hide=CSS选择器 : 隐藏匹配的元素(保留占位)，可重复指定")
+		fmt.Println("  --remove=CSS选择器: 删除匹配的元素，可重复指定")
+		fmt.Println("  --hide-consent=true/false: 隐藏常见的Cookie同意弹窗")
+		fmt.Println("  --element=CSS选择器: 只截取匹配的元素")
+		fmt.Println("  --all-elements=true/false: 截取所有匹配的元素，分别保存为多个文件")
+		fmt.Println("  --max-elements=数值: 截取所有匹配的元素时的元素数上限，默认50")
+		fmt.Println("  --padding=数值   : 元素截图四周的留白(像素)")
+		fmt.Println("  --clip=x,y,宽,高 : 只截取页面上的指定区域")
+		fmt.Println("  --diagnostics=true/false: 显示控制台消息、JS异常、失败的请求和页面加载时间")
+		fmt.Println("  --har=文件       : 保存网络请求记录(HAR)，同时开启诊断")
+		fmt.Println("  --format=png/jpeg/webp/pdf: 输出格式，默认根据输出文件扩展名确定")
+		fmt.Println("  --quality=数值   : JPEG/WebP压缩质量(1-100)")
+		fmt.Println("  --paper=A4/Letter等: PDF纸张尺寸")
+		fmt.Println("  --landscape=true/false: PDF横向打印")
+		fmt.Println("  --margin=数值    : PDF页边距(英寸)")
+		fmt.Println("  --print-background=true/false: PDF是否打印背景")
+		fmt.Println("  --header-template=HTML: PDF页眉模板")
+		fmt.Println("  --footer-template=HTML: PDF页脚模板")
+		fmt.Println("截图后处理选项（只支持PNG和JPEG）:")
+		fmt.Println("  --crop=x,y,宽,高 : 裁剪截图(图片像素)，在缩放之前进行")
+		fmt.Println("  --resize=宽x高   : 缩放截图，如 800x600、800、x600")
+		fmt.Println("  --fit=inside/contain/cover/fill: 缩放方式，默认inside")
+		fmt.Println("  --thumbnail=宽x高: 另外生成缩略图并保存为 输出文件名-thumb")
+		fmt.Println("  --watermark=true/false: 在图片底部添加URL和截图时间")
+		fmt.Println("  --optimize=true/false: PNG使用最高压缩率，颜色较少时转为调色板")
+		fmt.Println("存储选项（访问密钥读取 AWS_ACCESS_KEY_ID、AWS_SECRET_ACCESS_KEY 环境变量）:")
+		fmt.Println("  --storage=local/s3: 把截图保存到存储后端而不是输出文件")
+		fmt.Println("  --storage-dir=目录: 本地存储的目录，默认screenshots")
+		fmt.Println("  --storage-url=地址: 对象的公开访问地址前缀")
+		fmt.Println("  --storage-template=模板: 对象键的命名模板，默认 " + storage.DefaultTemplate)
+		fmt.Println("                    可用变量: {id} {host} {path} {date} {time} {unix} {hash} {ext}")
+		fmt.Println("  --s3-endpoint=地址: S3兼容存储的地址，如 http://localhost:9000")
+		fmt.Println("  --s3-region=区域 : S3的区域，默认us-east-1")
+		fmt.Println("  --s3-bucket=名称 : S3存储桶")
+		fmt.Println("  --s3-path-style=true/false: 使用路径形式的存储桶地址(MinIO等需要)")
+		fmt.Println("比较选项:")
+		fmt.Println("  --compare=基准图片: 截图后与基准图片比较，差异超过 --max-mismatch 时退出码为1")
+		fmt.Println("  --diff=文件      : 差异图的保存位置，默认为 基准图片-diff.png")
+		fmt.Println("  --threshold=数值 : 颜色差异的阈值(0-1)，越小越敏感，默认0.1")
+		fmt.Println("  --include-aa=true/false: 把抗锯齿像素也算作差异")
+		fmt.Println("  --ignore=x,y,宽,高: 不参与比较的区域(图片像素)，可重复指定")
+		fmt.Println("  --ignore-selector=CSS选择器: 不参与比较的元素，可重复指定")
+		fmt.Println("  --max-mismatch=百分比: 允许的差异像素比例，默认0")
 		os.Exit(1)
 	}
 
 	url := os.Args[1]
-	outputFile := "screenshot.png"
+	outputFile, harFile := "", ""
+	resizeValue, thumbnailValue, fitValue := "", "", ""
+	compare := newCompareConfig()
+	store := newStoreConfig()
 	if len(os.Args) >= 3 && !strings.HasPrefix(os.Args[2], "--") {
 		outputFile = os.Args[2]
 	}
 
 	// 使用默认选项
 	options := screenshot.DefaultOptions()
+
+	// 未指定格式时根据输出文件扩展名确定
+	if ext := filepath.Ext(outputFile); ext != "" {
+		if format, err := screenshot.ParseFormat(strings.TrimPrefix(ext, ".")); err == nil {
+			options.Format = format
+		}
+	}
+	
+	// 任一滚动参数都会开启滚动，未指定的参数使用默认值
+	scroll := func() *screenshot.ScrollOptions {
+		if options.Scroll == nil {
+			s := screenshot.DefaultScrollOptions()
+			options.Scroll = &s
+		}
+		options.FullPage = true
+		return options.Scroll
+	}
 	
 	// 解析命令行参数
 	for i := 2; i < len(os.Args); i++ {
@@ -47,6 +155,9 @@ func main() {
 		}
 		
 		key, value := parts[0], parts[1]
+		if compare.parse(key, value) || store.parse(key, value) {
+			continue
+		}
 		
 		switch key {
 		case "width":
@@ -59,6 +170,30 @@ func main() {
 			}
 		case "full":
 			options.FullPage = (value == "true" || value == "1")
+		case "max-height":
+			if h, err := strconv.Atoi(value); err == nil && h > 0 {
+				options.MaxHeight = h
+			}
+		case "scroll":
+			if value == "true" || value == "1" {
+				scroll()
+			}
+		case "scroll-step":
+			if s, err := strconv.Atoi(value); err == nil && s > 0 {
+				scroll().Step = s
+			}
+		case "scroll-delay":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				scroll().Delay = screenshot.ParseDuration(ms, "ms")
+			}
+		case "scroll-max":
+			if n, err := strconv.Atoi(value); err == nil && n > 0 {
+				scroll().MaxSteps = n
+			}
+		case "hide-fixed":
+			if value == "true" || value == "1" {
+				scroll().HideFixed = true
+			}
 		case "mobile":
 			options.MobileMode = (value == "true" || value == "1")
 		case "wait":
@@ -69,35 +204,323 @@ func main() {
 			options.BlockImages = (value == "true" || value == "1")
 		case "block-js":
 			options.BlockJS = (value == "true" || value == "1")
+		case "block":
+			types, err := screenshot.ParseResourceTypes(value)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			options.BlockResources = types
+		case "blocklist":
+			list, err := screenshot.LoadBlocklist(value)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			options.Blocklist = list
+		case "header":
+			name, v, ok := strings.Cut(value, ":")
+			if !ok {
+				log.Fatalf("无效的请求头: %s", value)
+			}
+			if options.Headers == nil {
+				options.Headers = make(map[string]string)
+			}
+			options.Headers[strings.TrimSpace(name)] = strings.TrimSpace(v)
+		case "cookie":
+			name, v, ok := strings.Cut(value, "=")
+			if !ok {
+				log.Fatalf("无效的Cookie: %s", value)
+			}
+			options.Cookies = append(options.Cookies, screenshot.Cookie{Name: name, Value: v})
+		case "basic-auth":
+			user, password, ok := strings.Cut(value, ":")
+			if !ok {
+				log.Fatalf("无效的认证信息，格式为 用户名:密码")
+			}
+			options.BasicAuth = &screenshot.BasicAuth{Username: user, Password: password}
+		case "local-storage", "session-storage":
+			k, v, ok := strings.Cut(value, "=")
+			if !ok {
+				log.Fatalf("无效的存储项: %s", value)
+			}
+			storage := &options.LocalStorage
+			if key == "session-storage" {
+				storage = &options.SessionStorage
+			}
+			if *storage == nil {
+				*storage = make(map[string]string)
+			}
+			(*storage)[k] = v
+		case "disable-web-security":
+			options.DisableWebSecurity = (value == "true" || value == "1")
 		case "selector":
 			options.Selector = value
+		case "steps":
+			data, err := os.ReadFile(value)
+			if err != nil {
+				log.Fatalf("无法读取步骤文件: %v", err)
+			}
+			steps, err := screenshot.ParseSteps(data)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			options.Steps = steps
+		case "wait-until":
+			events, err := screenshot.ParseWaitEvents(value)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			options.WaitUntil = events
+		case "wait-for":
+			options.WaitExpression = value
+		case "network-idle":
+			if ms, err := strconv.Atoi(value); err == nil && ms > 0 {
+				options.NetworkIdle = screenshot.ParseDuration(ms, "ms")
+			}
+		case "wait-deadline":
+			if d, err := strconv.Atoi(value); err == nil && d > 0 {
+				options.WaitDeadline = screenshot.ParseDuration(d, "s")
+			}
+		case "device":
+			device, err := screenshot.LookupDevice(value)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			options.Device = device
+		case "orientation":
+			options.Landscape = (value == "landscape")
+		case "dpr":
+			if dpr, err := strconv.ParseFloat(value, 64); err == nil && dpr > 0 {
+				options.DeviceScaleFactor = dpr
+			}
+		case "color-scheme":
+			scheme, err := screenshot.ParseColorScheme(value)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			options.ColorScheme = scheme
+		case "reduced-motion":
+			options.ReducedMotion = (value == "true" || value == "1")
+		case "media":
+			media, err := screenshot.ParseMediaType(value)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			options.Media = media
+		case "locale":
+			locale, err := screenshot.ParseLocale(value)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			options.Locale = locale
+		case "timezone":
+			options.Timezone = value
+		case "geolocation":
+			geo, err := screenshot.ParseGeolocation(value)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			options.Geolocation = geo
+		case "disable-animations":
+			options.DisableAnimations = (value == "true" || value == "1")
+		case "css", "script":
+			// 以@开头时从文件读取
+			if strings.HasPrefix(value, "@") {
+				data, err := os.ReadFile(value[1:])
+				if err != nil {
+					log.Fatalf("无法读取文件: %v", err)
+				}
+				value = string(data)
+			}
+			if key == "css" {
+				options.CSS = value
+			} else {
+				options.Script = value
+			}
+		case "hide":
+			options.Hide = append(options.Hide, value)
+		case "remove":
+			options.Remove = append(options.Remove, value)
+		case "hide-consent":
+			options.HideConsent = (value == "true" || value == "1")
+		case "element":
+			options.Element = value
+		case "max-elements":
+			if n, err := strconv.Atoi(value); err == nil && n > 0 {
+				options.MaxElements = n
+			}
+		case "all-elements":
+			options.AllElements = (value == "true" || value == "1")
+		case "padding":
+			if p, err := strconv.Atoi(value); err == nil && p >= 0 {
+				options.Padding = p
+			}
+		case "clip":
+			clip, err := screenshot.ParseClip(value)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			options.Clip = clip
+		case "diagnostics":
+			options.Diagnostics = (value == "true" || value == "1")
+		case "har":
+			harFile = value
+			options.Diagnostics = true
+		case "format":
+			if format, err := screenshot.ParseFormat(value); err == nil {
+				options.Format = format
+			}
+		case "quality":
+			if q, err := strconv.Atoi(value); err == nil && q > 0 && q <= 100 {
+				options.Quality = q
+			}
+		case "paper":
+			if err := options.PDF.SetPaper(value); err != nil {
+				log.Printf("%v，使用默认纸张尺寸", err)
+			}
+		case "landscape":
+			options.PDF.Landscape = (value == "true" || value == "1")
+		case "margin":
+			if m, err := strconv.ParseFloat(value, 64); err == nil && m >= 0 {
+				options.PDF.MarginTop, options.PDF.MarginBottom = m, m
+				options.PDF.MarginLeft, options.PDF.MarginRight = m, m
+			}
+		case "print-background":
+			options.PDF.PrintBackground = (value == "true" || value == "1")
+		case "header-template":
+			options.PDF.HeaderTemplate = value
+		case "footer-template":
+			options.PDF.FooterTemplate = value
+		case "ignore-selector":
+			options.Locate = append(options.Locate, value)
+		case "crop":
+			clip, err := screenshot.ParseClip(value)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			options.Crop = clip
+		case "resize":
+			resizeValue = value
+		case "fit":
+			fitValue = value
+		case "thumbnail":
+			thumbnailValue = value
+		case "watermark":
+			options.Watermark = (value == "true" || value == "1")
+		case "optimize":
+			options.OptimizePNG = (value == "true" || value == "1")
+		}
+	}
+
+	// --fit 可以写在 --resize 之后，解析完所有参数再处理尺寸
+	if resizeValue != "" {
+		fit, err := screenshot.ParseFitMode(fitValue, screenshot.FitInside)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		if options.Resize, err = screenshot.ParseResize(resizeValue, fit); err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+	if thumbnailValue != "" {
+		thumbnail, err := screenshot.ParseResize(thumbnailValue, screenshot.FitCover)
+		if err != nil {
+			log.Fatalf("%v", err)
 		}
+		options.Thumbnail = thumbnail
+	}
+
+	if outputFile == "" {
+		outputFile = "screenshot." + options.Format.Extension()
+	}
+	if err := store.open(); err != nil {
+		log.Fatalf("%v", err)
 	}
 
 	// 获取网页截图
 	fmt.Printf("开始截图: %s\n", url)
 	
-	buf, timing, err := screenshot.CaptureScreenshot(url, options)
+	result, err := screenshot.Capture(url, options)
 	if err != nil {
 		log.Fatalf("截图失败: %v", err)
 	}
+	timing := result.Timing
+	storedKey := ""
 
-	// 保存截图到文件
-	if err := os.WriteFile(outputFile, buf, 0644); err != nil {
-		log.Fatalf("无法保存截图: %v", err)
+	if options.AllElements {
+		// 每个元素保存为单独的文件：output-1.png、output-2.png ...
+		ext := filepath.Ext(outputFile)
+		base := strings.TrimSuffix(outputFile, ext)
+		for i, shot := range result.Shots {
+			name := fmt.Sprintf("%s-%d%s", base, i+1, ext)
+			if err := os.WriteFile(name, shot.Image, 0644); err != nil {
+				log.Fatalf("无法保存截图: %v", err)
+			}
+		}
+		fmt.Printf("成功截取网页 %s 中的 %d 个元素并保存到 %s-*%s\n", url, len(result.Shots), base, ext)
+	} else if store.enabled() {
+		// 保存截图到存储后端
+		storedKey = store.key(url, result.Image, options.Format.Extension())
+		fmt.Printf("成功截图网页 %s 并保存到 %s\n", url, store.put(storedKey, result.Image, options.Format.ContentType()))
+	} else {
+		// 保存截图到文件
+		if err := os.WriteFile(outputFile, result.Image, 0644); err != nil {
+			log.Fatalf("无法保存截图: %v", err)
+		}
+		fmt.Printf("成功截图网页 %s 并保存到 %s\n", url, outputFile)
+	}
+
+	if result.Thumbnail != nil && storedKey != "" {
+		ext := path.Ext(storedKey)
+		name := store.put(strings.TrimSuffix(storedKey, ext)+"-thumb"+ext, result.Thumbnail, options.Format.ContentType())
+		fmt.Printf("缩略图已保存到 %s\n", name)
+	} else if result.Thumbnail != nil {
+		ext := filepath.Ext(outputFile)
+		name := strings.TrimSuffix(outputFile, ext) + "-thumb" + ext
+		if err := os.WriteFile(name, result.Thumbnail, 0644); err != nil {
+			log.Fatalf("无法保存缩略图: %v", err)
+		}
+		fmt.Printf("缩略图已保存到 %s\n", name)
+	}
+
+	// 交互步骤中的截图：output-step-1.png ...
+	if len(options.Steps) > 0 {
+		base := strings.TrimSuffix(outputFile, filepath.Ext(outputFile))
+		for _, shot := range result.Shots {
+			name := base + "-" + filepath.Base(shot.Name)
+			if err := os.WriteFile(name, shot.Image, 0644); err != nil {
+				log.Fatalf("无法保存截图: %v", err)
+			}
+			fmt.Printf("步骤截图已保存到 %s\n", name)
+		}
+	}
+
+	if harFile != "" {
+		data, err := json.MarshalIndent(result.Diagnostics.HAR, "", "  ")
+		if err != nil {
+			log.Fatalf("无法生成HAR: %v", err)
+		}
+		if err := os.WriteFile(harFile, data, 0644); err != nil {
+			log.Fatalf("无法保存HAR: %v", err)
+		}
+		fmt.Printf("网络请求记录已保存到 %s（%d 个请求）\n", harFile, len(result.Diagnostics.HAR.Log.Entries))
 	}
 
 	// 显示耗时统计
-	fmt.Printf("成功截图网页 %s 并保存到 %s\n", url, outputFile)
 	fmt.Printf("\n=== 耗时统计 ===\n")
 	fmt.Printf("启动浏览器: %.2f 秒\n", timing.BrowserStart.Seconds())
-	fmt.Printf("页面导航: %.2f 秒\n", timing.Navigation.Seconds())
-	fmt.Printf("页面等待: %.2f 秒\n", timing.WaitComplete.Seconds())
+	fmt.Printf("页面导航: %.2f 秒（DNS %.3f、连接 %.3f、TLS %.3f、等待响应 %.3f）\n",
+		timing.Navigation.Seconds(), timing.DNS.Seconds(), timing.Connect.Seconds(), timing.TLS.Seconds(), timing.TTFB.Seconds())
+	fmt.Printf("DOMContentLoaded: %.2f 秒\n", timing.DOMContentLoaded.Seconds())
+	fmt.Printf("load: %.2f 秒\n", timing.Load.Seconds())
+	fmt.Printf("页面就绪: %.2f 秒\n", timing.WaitComplete.Seconds())
+	if timing.WaitTimedOut {
+		fmt.Println("(等待超时，页面可能尚未完全就绪)")
+	}
 	fmt.Printf("截图操作: %.2f 秒\n", timing.ScreenshotTime.Seconds())
 	fmt.Printf("总耗时: %.2f 秒\n", timing.TotalTime.Seconds())
 	
 	// 显示优化统计
-	if options.BlockImages || options.BlockJS {
+	if options.BlockImages || options.BlockJS || len(options.BlockResources) > 0 || options.Blocklist != nil {
 		fmt.Printf("\n=== 优化策略 ===\n")
 		if options.BlockImages {
 			fmt.Println("- 已屏蔽图片加载")
@@ -105,5 +528,254 @@ func main() {
 		if options.BlockJS {
 			fmt.Println("- 已屏蔽JavaScript")
 		}
+		for _, t := range options.BlockResources {
+			fmt.Printf("- 已屏蔽 %s 类型的资源\n", t)
+		}
+		fmt.Printf("- 按资源类型屏蔽了 %d 个请求\n", timing.BlockedResources)
+		if options.Blocklist != nil {
+			fmt.Printf("- 按屏蔽列表（%d 个域名）屏蔽了 %d 个广告和跟踪请求\n", options.Blocklist.Len(), timing.BlockedByList)
+		}
+	}
+
+	// 显示诊断信息
+	if d := result.Diagnostics; d != nil {
+		printDiagnostics(d)
+	}
+
+	// 与基准图片比较，忽略 --ignore-selector 匹配的元素
+	if compare.baseline != "" {
+		for _, r := range result.Regions {
+			compare.options.Ignore = append(compare.options.Ignore, diff.Rect(r.X, r.Y, r.Width, r.Height))
+		}
+		if !compare.run(result.Image) {
+			os.Exit(1)
+		}
+	}
+}
+
+// storeConfig 截图存储的命令行选项
+type storeConfig struct {
+	config   storage.Config
+	template string
+	storage  storage.Storage
+	keys     *storage.Template
+}
+
+func newStoreConfig() *storeConfig {
+	return &storeConfig{
+		config: storage.Config{
+			Dir: "screenshots",
+			S3: storage.S3Options{
+				AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+				SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+				SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+			},
+		},
+		template: storage.DefaultTemplate,
+	}
+}
+
+// parse 解析存储选项，返回是否为存储选项
+func (c *storeConfig) parse(key, value string) bool {
+	switch key {
+	case "storage":
+		c.config.Type = value
+	case "storage-dir":
+		c.config.Dir = value
+	case "storage-url":
+		c.config.BaseURL = value
+	case "storage-template":
+		c.template = value
+	case "s3-endpoint":
+		c.config.S3.Endpoint = value
+	case "s3-region":
+		c.config.S3.Region = value
+	case "s3-bucket":
+		c.config.S3.Bucket = value
+	case "s3-path-style":
+		c.config.S3.PathStyle = (value == "true" || value == "1")
+	default:
+		return false
+	}
+	return true
+}
+
+// open 创建存储后端，未指定 --storage 时不做任何事
+func (c *storeConfig) open() error {
+	if c.config.Type == "" {
+		return nil
+	}
+	keys, err := storage.ParseTemplate(c.template)
+	if err != nil {
+		return err
+	}
+	s, err := storage.New(c.config)
+	if err != nil {
+		return err
+	}
+	c.storage, c.keys = s, keys
+	return nil
+}
+
+func (c *storeConfig) enabled() bool {
+	return c.storage != nil
+}
+
+// key 按命名模板生成对象键
+func (c *storeConfig) key(url string, data []byte, ext string) string {
+	key, err := c.keys.Key(storage.Vars{URL: url, Extension: ext, Data: data, Time: time.Now()})
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	return key
+}
+
+// put 保存图片，返回对象的访问地址，没有地址时返回键
+func (c *storeConfig) put(key string, data []byte, contentType string) string {
+	obj, err := c.storage.Put(context.Background(), key, data, contentType)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if obj.URL != "" {
+		return obj.URL
+	}
+	return obj.Key
+}
+
+// compareConfig 视觉比较的命令行选项
+type compareConfig struct {
+	baseline    string
+	diffFile    string
+	maxMismatch float64
+	options     diff.Options
+}
+
+func newCompareConfig() *compareConfig {
+	return &compareConfig{options: diff.DefaultOptions()}
+}
+
+// parse 解析比较选项，不是比较选项时返回 false
+func (c *compareConfig) parse(key, value string) bool {
+	switch key {
+	case "compare":
+		c.baseline = value
+	case "diff":
+		c.diffFile = value
+	case "threshold":
+		if t, err := strconv.ParseFloat(value, 64); err == nil && t >= 0 && t <= 1 {
+			c.options.Threshold = t
+		}
+	case "include-aa":
+		c.options.IncludeAA = (value == "true" || value == "1")
+	case "ignore":
+		rect, err := diff.ParseRect(value)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		c.options.Ignore = append(c.options.Ignore, rect)
+	case "max-mismatch":
+		if m, err := strconv.ParseFloat(value, 64); err == nil && m >= 0 {
+			c.maxMismatch = m
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// run 比较图片与基准图片并保存差异图，差异在允许范围内时返回 true
+func (c *compareConfig) run(current []byte) bool {
+	baselineData, err := os.ReadFile(c.baseline)
+	if err != nil {
+		log.Fatalf("无法读取基准图片: %v", err)
+	}
+	baseline, err := diff.Decode(baselineData)
+	if err != nil {
+		log.Fatalf("基准图片: %v", err)
+	}
+	img, err := diff.Decode(current)
+	if err != nil {
+		log.Fatalf("截图: %v", err)
+	}
+
+	result := diff.Compare(img, baseline, c.options)
+	if c.diffFile == "" {
+		c.diffFile = strings.TrimSuffix(c.baseline, filepath.Ext(c.baseline)) + "-diff.png"
+	}
+	data, err := diff.EncodePNG(result.Image)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := os.WriteFile(c.diffFile, data, 0644); err != nil {
+		log.Fatalf("无法保存差异图: %v", err)
+	}
+
+	fmt.Printf("\n=== 视觉比较 ===\n")
+	fmt.Printf("基准图片: %s\n", c.baseline)
+	if result.SizeMismatch {
+		fmt.Printf("图片尺寸不同: %dx%d / %dx%d\n", img.Bounds().Dx(), img.Bounds().Dy(), baseline.Bounds().Dx(), baseline.Bounds().Dy())
+	}
+	fmt.Printf("差异像素: %d / %d (%.3f%%)\n", result.DiffPixels, result.TotalPixels, result.MismatchPercent)
+	fmt.Printf("颜色变化的像素: %d, 抗锯齿像素: %d\n", result.ChangedPixels, result.AAPixels)
+	if result.DiffPixels > 0 {
+		b := result.Bounds
+		fmt.Printf("差异区域: x=%d y=%d 宽=%d 高=%d\n", b.Min.X, b.Min.Y, b.Dx(), b.Dy())
+	}
+	fmt.Printf("差异图已保存到 %s\n", c.diffFile)
+
+	if result.MismatchPercent > c.maxMismatch {
+		fmt.Printf("比较失败: 差异 %.3f%% 超过允许的 %.3f%%\n", result.MismatchPercent, c.maxMismatch)
+		return false
+	}
+	fmt.Println("比较通过")
+	return true
+}
+
+// compareFiles 比较两张已有的图片：compare <图片> <基准图片> [比较选项]
+func compareFiles(args []string) {
+	if len(args) < 2 {
+		fmt.Println("使用方法: go run main.go compare <图片> <基准图片> [--diff=文件] [--threshold=0.1] [--ignore=x,y,宽,高] [--max-mismatch=百分比]")
+		os.Exit(1)
+	}
+
+	compare := newCompareConfig()
+	compare.baseline = args[1]
+	for _, arg := range args[2:] {
+		key, value, ok := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if !ok || !compare.parse(key, value) {
+			log.Fatalf("未知的比较选项: %s", arg)
+		}
+	}
+
+	current, err := os.ReadFile(args[0])
+	if err != nil {
+		log.Fatalf("无法读取图片: %v", err)
+	}
+	if !compare.run(current) {
+		os.Exit(1)
+	}
+}
+
+// printDiagnostics 显示页面诊断信息的摘要
+func printDiagnostics(d *screenshot.Diagnostics) {
+	fmt.Printf("\n=== 页面诊断 ===\n")
+	nav := d.Navigation
+	fmt.Printf("TTFB: %.0f ms, DOMContentLoaded: %.0f ms, Load: %.0f ms, FCP: %.0f ms, LCP: %.0f ms\n",
+		nav.TTFB, nav.DOMContentLoaded, nav.Load, nav.FirstContentfulPaint, nav.LargestContentfulPaint)
+	fmt.Printf("网络请求: %d 个, 失败: %d 个\n", len(d.HAR.Log.Entries), len(d.FailedRequests))
+	for _, f := range d.FailedRequests {
+		fmt.Printf("  [%s] %s %s\n", f.Error, f.Method, f.URL)
+	}
+	if len(d.Exceptions) > 0 {
+		fmt.Printf("JS异常: %d 个\n", len(d.Exceptions))
+		for _, e := range d.Exceptions {
+			fmt.Printf("  %s (%s:%d)\n", e.Message, e.URL, e.Line)
+		}
+	}
+	if len(d.Console) > 0 {
+		fmt.Printf("控制台消息: %d 条\n", len(d.Console))
+		for _, m := range d.Console {
+			fmt.Printf("  [%s] %s\n", m.Level, m.Text)
+		}
 	}
-} 
\ No newline at end of file
+}
@@ -0,0 +1,118 @@
+package screenshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// Cookie 导航前写入浏览器的Cookie，Domain为空时使用截图URL的域名
+type Cookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Domain   string `json:"domain,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+	HTTPOnly bool   `json:"http_only,omitempty"`
+}
+
+// BasicAuth HTTP基本认证的用户名和密码，只提供给截图URL所在的源
+type BasicAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// storageJS 在每个新文档中写入localStorage和sessionStorage，只对截图URL所在的源生效
+const storageJS = `((origin, local, session) => {
+	if (location.origin !== origin) return;
+	try {
+		for (const [k, v] of Object.entries(local)) localStorage.setItem(k, v);
+		for (const [k, v] of Object.entries(session)) sessionStorage.setItem(k, v);
+	} catch (e) {}
+})(%s, %s, %s)`
+
+// authAction 在导航前设置Cookie和本地存储。请求头由拦截器只附加到截图URL所在的源的请求
+func authAction(pageURL string, options Options) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if len(options.Cookies) > 0 {
+			cookies := make([]*network.CookieParam, 0, len(options.Cookies))
+			for _, c := range options.Cookies {
+				cookie := &network.CookieParam{
+					Name:     c.Name,
+					Value:    c.Value,
+					Domain:   c.Domain,
+					Path:     c.Path,
+					Secure:   c.Secure,
+					HTTPOnly: c.HTTPOnly,
+				}
+				if c.Domain == "" {
+					cookie.URL = pageURL
+				}
+				cookies = append(cookies, cookie)
+			}
+			if err := network.SetCookies(cookies).Do(ctx); err != nil {
+				return fmt.Errorf("设置Cookie失败: %w", err)
+			}
+		}
+
+		if len(options.LocalStorage) > 0 || len(options.SessionStorage) > 0 {
+			origin, err := urlOrigin(pageURL)
+			if err != nil {
+				return err
+			}
+			script, err := storageScript(origin, options.LocalStorage, options.SessionStorage)
+			if err != nil {
+				return err
+			}
+			if _, err := page.AddScriptToEvaluateOnNewDocument(script).Do(ctx); err != nil {
+				return fmt.Errorf("设置本地存储失败: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// storageScript 生成写入本地存储的脚本
+func storageScript(origin string, local, session map[string]string) (string, error) {
+	args := make([]interface{}, 0, 3)
+	for _, v := range []interface{}{origin, nonNilMap(local), nonNilMap(session)} {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, b)
+	}
+	return fmt.Sprintf(storageJS, args...), nil
+}
+
+func nonNilMap(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
+
+// urlOrigin 返回URL的源，形如 https://example.com:8443
+func urlOrigin(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("无效的URL: %s", rawURL)
+	}
+	// 与 location.origin 一致，省略默认端口
+	host := u.Host
+	if u.Scheme == "http" && u.Port() == "80" || u.Scheme == "https" && u.Port() == "443" {
+		host = u.Hostname()
+	}
+	return strings.ToLower(u.Scheme + "://" + host), nil
+}
+
+// usesAuth 是否需要在导航前设置认证信息
+func (o Options) usesAuth() bool {
+	return len(o.Cookies) > 0 || len(o.LocalStorage) > 0 || len(o.SessionStorage) > 0
+}
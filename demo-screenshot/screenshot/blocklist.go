@@ -0,0 +1,142 @@
+package screenshot
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Blocklist 广告和跟踪器的域名屏蔽列表，匹配域名本身及其所有子域名
+type Blocklist struct {
+	blocked map[string]bool
+	allowed map[string]bool // @@ 开头的例外规则
+}
+
+// LoadBlocklist 从文件加载屏蔽列表
+func LoadBlocklist(path string) (*Blocklist, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开屏蔽列表失败: %w", err)
+	}
+	defer f.Close()
+
+	list, err := ParseBlocklist(f)
+	if err != nil {
+		return nil, fmt.Errorf("读取屏蔽列表 %s 失败: %w", path, err)
+	}
+	return list, nil
+}
+
+// ParseBlocklist 解析EasyList风格的屏蔽列表，支持以下几种行：
+//
+//	||ads.example.com^            屏蔽域名及其子域名
+//	||tracker.example^$third-party
+//	@@||cdn.example.com^          例外规则
+//	0.0.0.0 ads.example.com       hosts文件格式
+//	ads.example.com               单独的域名
+//
+// 带路径、通配符或 domain= 等限定条件的规则会被忽略
+func ParseBlocklist(r io.Reader) (*Blocklist, error) {
+	list := &Blocklist{
+		blocked: make(map[string]bool),
+		allowed: make(map[string]bool),
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// 空行、注释和列表头
+		if line == "" || strings.HasPrefix(line, "!") || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") {
+			continue
+		}
+
+		target := list.blocked
+		if strings.HasPrefix(line, "@@") {
+			target = list.allowed
+			line = line[2:]
+		}
+
+		if domain, ok := parseBlocklistRule(line); ok {
+			target[domain] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// parseBlocklistRule 从一条规则中提取域名
+func parseBlocklistRule(line string) (string, bool) {
+	// hosts文件格式：IP 域名
+	if fields := strings.Fields(line); len(fields) >= 2 && net.ParseIP(fields[0]) != nil {
+		return normalizeDomain(fields[1])
+	}
+
+	if !strings.HasPrefix(line, "||") {
+		// 不带任何语法的单独域名
+		if strings.ContainsAny(line, "|^$/*#") {
+			return "", false
+		}
+		return normalizeDomain(line)
+	}
+
+	rule, opts, _ := strings.Cut(line[2:], "$")
+	for _, opt := range strings.Split(opts, ",") {
+		switch strings.TrimSpace(opt) {
+		case "", "third-party", "3p", "important", "all", "document":
+		default:
+			// 只对部分页面或资源类型生效的规则
+			return "", false
+		}
+	}
+
+	domain, ok := strings.CutSuffix(rule, "^")
+	if !ok || strings.ContainsAny(domain, "/*^|") {
+		return "", false
+	}
+	return normalizeDomain(domain)
+}
+
+func normalizeDomain(domain string) (string, bool) {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	if domain == "" || domain == "localhost" || !strings.Contains(domain, ".") {
+		return "", false
+	}
+	return domain, true
+}
+
+// Len 返回屏蔽的域名数量
+func (b *Blocklist) Len() int {
+	return len(b.blocked)
+}
+
+// Blocks 判断URL的主机名是否在屏蔽列表中
+func (b *Blocklist) Blocks(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+
+	// 从完整主机名开始逐级检查上级域名，例外规则优先
+	for host != "" {
+		if b.allowed[host] {
+			return false
+		}
+		if b.blocked[host] {
+			return true
+		}
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok {
+			break
+		}
+		host = parent
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package screenshot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// CacheKey 返回URL和截图选项的哈希值，输出相同的请求得到相同的值。
+// 对结果没有影响的选项（超时、URL策略等）不参与计算。
+// Diagnostics 参与计算，需要诊断信息的请求不会取到普通截图的缓存（缓存只保存图片，这类请求应直接截图）
+func CacheKey(rawURL string, options Options) string {
+	key := struct {
+		URL       string
+		Options   Options
+		Blocklist bool
+	}{
+		URL:       normalizeURL(rawURL),
+		Options:   normalizeOptions(options),
+		Blocklist: options.Blocklist != nil,
+	}
+	// Blocklist 和 URLPolicy 已清除，其余指针字段（Device、BasicAuth、Crop等）按指向的值序列化，
+	// 选项中没有函数和通道，序列化不会失败，map按键排序
+	data, _ := json.Marshal(key)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeURL 协议和主机名转为小写，去掉默认端口，空路径视为 /
+func normalizeURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if u.Scheme == "http" {
+		u.Host = strings.TrimSuffix(u.Host, ":80")
+	} else if u.Scheme == "https" {
+		u.Host = strings.TrimSuffix(u.Host, ":443")
+	}
+	if u.Path == "" && u.Opaque == "" {
+		u.Path = "/"
+	}
+	return u.String()
+}
+
+// normalizeOptions 清除不影响结果的选项，避免相同的截图得到不同的键
+func normalizeOptions(options Options) Options {
+	options.Timeout = 0
+	options.Blocklist = nil
+	options.URLPolicy = nil
+
+	if options.Format == "" {
+		options.Format = FormatPNG
+	}
+	if options.Format != FormatJPEG && options.Format != FormatWebP {
+		options.Quality = 0
+	}
+	if options.Format != FormatPDF {
+		options.PDF = PDFOptions{}
+	}
+	if !options.FullPage {
+		options.MaxHeight = 0
+	}
+	if options.Device != nil {
+		options.Width, options.Height, options.MobileMode = 0, 0, false
+	}
+	if len(options.WaitUntil) > 0 {
+		options.WaitTime = 0
+	}
+
+	options.BlockResources = append(options.BlockResources[:0:0], options.BlockResources...)
+	sort.Slice(options.BlockResources, func(i, j int) bool {
+		return options.BlockResources[i] < options.BlockResources[j]
+	})
+	return options
+}
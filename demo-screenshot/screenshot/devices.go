@@ -0,0 +1,138 @@
+package screenshot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+)
+
+// Device 设备仿真参数，尺寸为竖屏时的CSS像素
+type Device struct {
+	Name              string
+	Width             int
+	Height            int
+	DeviceScaleFactor float64
+	Mobile            bool // 移动端视口（meta viewport、滚动条等按手机处理）
+	Touch             bool // 触摸事件
+	UserAgent         string
+	Platform          string // navigator.platform
+}
+
+const (
+	iosUserAgent     = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1"
+	ipadUserAgent    = "Mozilla/5.0 (iPad; CPU OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1"
+	androidUserAgent = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36"
+)
+
+// Devices 内置的设备预设
+var Devices = map[string]Device{
+	"iphone-15":         {Name: "iPhone 15", Width: 393, Height: 852, DeviceScaleFactor: 3, Mobile: true, Touch: true, UserAgent: iosUserAgent, Platform: "iPhone"},
+	"iphone-15-pro-max": {Name: "iPhone 15 Pro Max", Width: 430, Height: 932, DeviceScaleFactor: 3, Mobile: true, Touch: true, UserAgent: iosUserAgent, Platform: "iPhone"},
+	"iphone-se":         {Name: "iPhone SE", Width: 375, Height: 667, DeviceScaleFactor: 2, Mobile: true, Touch: true, UserAgent: iosUserAgent, Platform: "iPhone"},
+	"pixel-8":           {Name: "Pixel 8", Width: 412, Height: 915, DeviceScaleFactor: 2.625, Mobile: true, Touch: true, UserAgent: androidUserAgent, Platform: "Linux armv8l"},
+	"ipad":              {Name: "iPad", Width: 820, Height: 1180, DeviceScaleFactor: 2, Mobile: true, Touch: true, UserAgent: ipadUserAgent, Platform: "iPad"},
+	"ipad-pro":          {Name: "iPad Pro 12.9", Width: 1024, Height: 1366, DeviceScaleFactor: 2, Mobile: true, Touch: true, UserAgent: ipadUserAgent, Platform: "iPad"},
+	"desktop":           {Name: "Desktop", Width: 1920, Height: 1080, DeviceScaleFactor: 1},
+	"desktop-hidpi":     {Name: "Desktop HiDPI", Width: 1440, Height: 900, DeviceScaleFactor: 2},
+}
+
+// 设备名称的别名
+var deviceAliases = map[string]string{
+	"iphone": "iphone-15",
+	"pixel":  "pixel-8",
+	"retina": "desktop-hidpi",
+}
+
+// DefaultMobileDevice MobileMode 使用的设备
+const DefaultMobileDevice = "iphone-15"
+
+// LookupDevice 按名称查找设备预设，不区分大小写
+func LookupDevice(name string) (*Device, error) {
+	key := strings.ToLower(name)
+	if alias, ok := deviceAliases[key]; ok {
+		key = alias
+	}
+	device, ok := Devices[key]
+	if !ok {
+		return nil, fmt.Errorf("未知的设备: %s（可用设备: %s）", name, strings.Join(DeviceNames(), ", "))
+	}
+	return &device, nil
+}
+
+// DeviceNames 返回所有设备预设的名称
+func DeviceNames() []string {
+	names := make([]string, 0, len(Devices))
+	for name := range Devices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// scaleFactor 返回截图使用的设备像素比：DeviceScaleFactor、设备预设的像素比或1
+func (o Options) scaleFactor() float64 {
+	if o.DeviceScaleFactor > 0 {
+		return o.DeviceScaleFactor
+	}
+	device := o.Device
+	if device == nil && o.MobileMode {
+		device, _ = LookupDevice(DefaultMobileDevice)
+	}
+	if device != nil && device.DeviceScaleFactor > 0 {
+		return device.DeviceScaleFactor
+	}
+	return 1
+}
+
+// emulateAction 设置视口尺寸、像素比、触摸和User-Agent等设备仿真参数
+func emulateAction(options Options) chromedp.Tasks {
+	device := options.Device
+	if device == nil && options.MobileMode {
+		device, _ = LookupDevice(DefaultMobileDevice)
+	}
+
+	width, height, scale := options.Width, options.Height, options.scaleFactor()
+	mobile, touch := false, false
+	userAgent, platform := options.UserAgent, ""
+	if device != nil {
+		width, height = device.Width, device.Height
+		mobile, touch = device.Mobile, device.Touch
+		platform = device.Platform
+		if userAgent == "" {
+			userAgent = device.UserAgent
+		}
+	}
+
+	// 横屏时交换宽高
+	if options.Landscape && height > width {
+		width, height = height, width
+	}
+	orientation := &emulation.ScreenOrientation{Type: emulation.OrientationTypePortraitPrimary, Angle: 0}
+	if width > height {
+		// 手机和平板横屏时屏幕旋转了90度，桌面显示器本身就是横向的
+		angle := int64(0)
+		if mobile {
+			angle = 90
+		}
+		orientation = &emulation.ScreenOrientation{Type: emulation.OrientationTypeLandscapePrimary, Angle: angle}
+	}
+
+	tasks := chromedp.Tasks{
+		emulation.SetDeviceMetricsOverride(int64(width), int64(height), scale, mobile).
+			WithScreenWidth(int64(width)).
+			WithScreenHeight(int64(height)).
+			WithScreenOrientation(orientation),
+	}
+	if touch {
+		tasks = append(tasks, emulation.SetTouchEmulationEnabled(true).WithMaxTouchPoints(5))
+	} else {
+		tasks = append(tasks, emulation.SetTouchEmulationEnabled(false))
+	}
+	if userAgent != "" || options.Locale != "" {
+		tasks = append(tasks, userAgentAction(userAgent, platform, options.Locale))
+	}
+	return tasks
+}
@@ -0,0 +1,315 @@
+package screenshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/log"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/performance"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// Diagnostics 截图过程中收集的页面诊断信息
+type Diagnostics struct {
+	HAR            *HAR               `json:"har"`
+	Console        []ConsoleMessage   `json:"console"`
+	Exceptions     []PageException    `json:"exceptions"`
+	FailedRequests []FailedRequest    `json:"failed_requests"`
+	Metrics        map[string]float64 `json:"metrics"`    // Performance.getMetrics 的结果
+	Navigation     NavigationTiming   `json:"navigation"` // 页面加载的关键时间点
+}
+
+// ConsoleMessage 控制台消息，包括页面脚本的 console 调用和浏览器的日志（如安全策略、网络错误）
+type ConsoleMessage struct {
+	Level  string `json:"level"`
+	Source string `json:"source"`
+	Text   string `json:"text"`
+	URL    string `json:"url,omitempty"`
+	Line   int64  `json:"line,omitempty"`
+}
+
+// PageException 页面中未捕获的JS异常
+type PageException struct {
+	Message string `json:"message"`
+	URL     string `json:"url,omitempty"`
+	Line    int64  `json:"line"`
+	Column  int64  `json:"column"`
+	Stack   string `json:"stack,omitempty"`
+}
+
+// FailedRequest 失败的网络请求，包括网络错误和4xx/5xx响应
+type FailedRequest struct {
+	URL          string `json:"url"`
+	Method       string `json:"method"`
+	ResourceType string `json:"resource_type"`
+	Status       int64  `json:"status,omitempty"`
+	Error        string `json:"error"`
+}
+
+// NavigationTiming 相对于导航开始的毫秒数，0表示未发生
+type NavigationTiming struct {
+	TTFB                   float64 `json:"ttfb"`
+	DOMContentLoaded       float64 `json:"dom_content_loaded"`
+	Load                   float64 `json:"load"`
+	FirstContentfulPaint   float64 `json:"first_contentful_paint"`
+	LargestContentfulPaint float64 `json:"largest_contentful_paint"`
+}
+
+// 每类诊断信息最多保留的条数
+const maxDiagnosticItems = 1000
+
+// navigationTimingJS 读取导航计时和绘制时间，LCP只能通过 PerformanceObserver 获取
+const navigationTimingJS = `new Promise(resolve => {
+	const nav = performance.getEntriesByType('navigation')[0];
+	const fcp = performance.getEntriesByName('first-contentful-paint')[0];
+	let lcp = 0;
+	try {
+		new PerformanceObserver(list => {
+			const entries = list.getEntries();
+			if (entries.length) lcp = entries[entries.length - 1].startTime;
+		}).observe({type: 'largest-contentful-paint', buffered: true});
+	} catch (e) {}
+	setTimeout(() => resolve({
+		ttfb: nav ? nav.responseStart : 0,
+		dom_content_loaded: nav ? nav.domContentLoadedEventEnd : 0,
+		load: nav ? nav.loadEventEnd : 0,
+		first_contentful_paint: fcp ? fcp.startTime : 0,
+		largest_contentful_paint: lcp,
+	}), 100);
+})`
+
+// diagnosticsCollector 监听网络、控制台和异常事件，在导航前创建
+type diagnosticsCollector struct {
+	mu         sync.Mutex
+	requests   map[network.RequestID]*harEntry
+	entries    []*harEntry
+	console    []ConsoleMessage
+	exceptions []PageException
+	failed     []FailedRequest
+
+	// 截图前由 collectAction 读取
+	title      string
+	metrics    map[string]float64
+	navigation NavigationTiming
+}
+
+// newDiagnosticsCollector 创建收集器并开始监听事件
+func newDiagnosticsCollector(ctx context.Context) *diagnosticsCollector {
+	c := &diagnosticsCollector{
+		requests: make(map[network.RequestID]*harEntry),
+	}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.handle(ev)
+	})
+	return c
+}
+
+func (c *diagnosticsCollector) handle(ev interface{}) {
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		// 重定向沿用同一个请求ID，先结束上一跳
+		if prev, ok := c.requests[ev.RequestID]; ok && ev.RedirectResponse != nil {
+			prev.response = ev.RedirectResponse
+			prev.finishedMono = monoSeconds(ev.Timestamp)
+		}
+		if len(c.entries) >= maxDiagnosticItems {
+			return
+		}
+		entry := &harEntry{
+			started:      time.Now(),
+			startedMono:  monoSeconds(ev.Timestamp),
+			request:      ev.Request,
+			resourceType: ev.Type,
+		}
+		if ev.WallTime != nil {
+			entry.started = ev.WallTime.Time()
+		}
+		c.requests[ev.RequestID] = entry
+		c.entries = append(c.entries, entry)
+
+	case *network.EventResponseReceived:
+		entry, ok := c.requests[ev.RequestID]
+		if !ok {
+			return
+		}
+		entry.response = ev.Response
+		if ev.Response.Status >= 400 {
+			c.addFailed(entry, ev.Response.Status, fmt.Sprintf("HTTP %d %s", ev.Response.Status, ev.Response.StatusText))
+		}
+
+	case *network.EventLoadingFinished:
+		if entry, ok := c.requests[ev.RequestID]; ok {
+			entry.finishedMono = monoSeconds(ev.Timestamp)
+			entry.size = int64(ev.EncodedDataLength)
+		}
+
+	case *network.EventLoadingFailed:
+		entry, ok := c.requests[ev.RequestID]
+		if !ok {
+			return
+		}
+		entry.finishedMono = monoSeconds(ev.Timestamp)
+		entry.err = ev.ErrorText
+		if ev.BlockedReason != "" {
+			entry.err += " (" + string(ev.BlockedReason) + ")"
+		}
+		c.addFailed(entry, 0, entry.err)
+
+	case *runtime.EventConsoleAPICalled:
+		msg := ConsoleMessage{
+			Level:  string(ev.Type),
+			Source: "console-api",
+			Text:   consoleText(ev.Args),
+		}
+		if ev.StackTrace != nil && len(ev.StackTrace.CallFrames) > 0 {
+			frame := ev.StackTrace.CallFrames[0]
+			msg.URL, msg.Line = frame.URL, frame.LineNumber+1
+		}
+		c.addConsole(msg)
+
+	case *log.EventEntryAdded:
+		c.addConsole(ConsoleMessage{
+			Level:  string(ev.Entry.Level),
+			Source: string(ev.Entry.Source),
+			Text:   ev.Entry.Text,
+			URL:    ev.Entry.URL,
+			Line:   ev.Entry.LineNumber,
+		})
+
+	case *runtime.EventExceptionThrown:
+		details := ev.ExceptionDetails
+		exception := PageException{
+			Message: details.Text,
+			URL:     details.URL,
+			Line:    details.LineNumber + 1,
+			Column:  details.ColumnNumber + 1,
+		}
+		if details.Exception != nil && details.Exception.Description != "" {
+			// Description 包含异常消息和调用栈
+			exception.Stack = details.Exception.Description
+			exception.Message, _, _ = strings.Cut(details.Exception.Description, "\n")
+		}
+		if len(c.exceptions) < maxDiagnosticItems {
+			c.exceptions = append(c.exceptions, exception)
+		}
+	}
+}
+
+func (c *diagnosticsCollector) addConsole(msg ConsoleMessage) {
+	if len(c.console) < maxDiagnosticItems {
+		c.console = append(c.console, msg)
+	}
+}
+
+func (c *diagnosticsCollector) addFailed(entry *harEntry, status int64, reason string) {
+	if len(c.failed) >= maxDiagnosticItems {
+		return
+	}
+	c.failed = append(c.failed, FailedRequest{
+		URL:          entry.request.URL,
+		Method:       entry.request.Method,
+		ResourceType: string(entry.resourceType),
+		Status:       status,
+		Error:        reason,
+	})
+}
+
+// consoleText 把 console 调用的参数拼接为文本
+func consoleText(args []*runtime.RemoteObject) string {
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch {
+		case arg.Type == runtime.TypeString:
+			var s string
+			if json.Unmarshal(arg.Value, &s) == nil {
+				parts = append(parts, s)
+			}
+		case arg.UnserializableValue != "":
+			parts = append(parts, string(arg.UnserializableValue))
+		case len(arg.Value) > 0 && arg.Type != runtime.TypeObject:
+			parts = append(parts, string(arg.Value))
+		case arg.Description != "":
+			parts = append(parts, arg.Description)
+		default:
+			parts = append(parts, string(arg.Type))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// collectAction 在截图前读取页面标题、性能指标和导航计时
+func (c *diagnosticsCollector) collectAction() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var title string
+		if err := chromedp.Title(&title).Do(ctx); err != nil {
+			return fmt.Errorf("读取页面标题失败: %w", err)
+		}
+
+		metrics := make(map[string]float64)
+		if err := performance.Enable().Do(ctx); err != nil {
+			return fmt.Errorf("启用性能指标失败: %w", err)
+		}
+		list, err := performance.GetMetrics().Do(ctx)
+		if err != nil {
+			return fmt.Errorf("读取性能指标失败: %w", err)
+		}
+		for _, m := range list {
+			metrics[m.Name] = m.Value
+		}
+
+		var navigation NavigationTiming
+		err = chromedp.Evaluate(navigationTimingJS, &navigation, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+			return p.WithAwaitPromise(true)
+		}).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("读取导航计时失败: %w", err)
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.title, c.metrics, c.navigation = title, metrics, navigation
+		return nil
+	})
+}
+
+// report 生成诊断报告
+func (c *diagnosticsCollector) report() *Diagnostics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	page := HARPage{
+		ID:    "page_1",
+		Title: c.title,
+		PageTimings: HARPageTimings{
+			OnContentLoad: harPageTiming(c.navigation.DOMContentLoaded),
+			OnLoad:        harPageTiming(c.navigation.Load),
+		},
+	}
+	if len(c.entries) > 0 {
+		page.StartedDateTime = c.entries[0].started
+	}
+
+	return &Diagnostics{
+		HAR:            buildHAR(page, c.entries),
+		Console:        c.console,
+		Exceptions:     c.exceptions,
+		FailedRequests: c.failed,
+		Metrics:        c.metrics,
+		Navigation:     c.navigation,
+	}
+}
+
+func harPageTiming(ms float64) float64 {
+	if ms <= 0 {
+		return -1
+	}
+	return ms
+}
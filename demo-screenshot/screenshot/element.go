@@ -0,0 +1,219 @@
+package screenshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// Clip 页面上的矩形区域，坐标相对于页面左上角（CSS像素）
+type Clip struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// ParseClip 解析 "x,y,width,height" 形式的矩形区域
+func ParseClip(value string) (*Clip, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("无效的截图区域: %s", value)
+	}
+
+	var nums [4]float64
+	for i, part := range parts {
+		n, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的截图区域: %s", value)
+		}
+		nums[i] = n
+	}
+
+	clip := &Clip{X: nums[0], Y: nums[1], Width: nums[2], Height: nums[3]}
+	if clip.Width <= 0 || clip.Height <= 0 {
+		return nil, fmt.Errorf("截图区域的宽高必须大于0: %s", value)
+	}
+	if err := clip.checkSize(1); err != nil {
+		return nil, err
+	}
+	return clip, nil
+}
+
+// checkSize 检查区域按像素比放大后的宽高不超过 maxImageSide
+func (c Clip) checkSize(scale float64) error {
+	if c.Width*scale > maxImageSide || c.Height*scale > maxImageSide {
+		return fmt.Errorf("截图区域 %gx%g 过大，宽高乘以像素比 %g 后不能超过 %d 像素", c.Width, c.Height, scale, maxImageSide)
+	}
+	return nil
+}
+
+// ValidateRegion 检查区域截图的区域和元素留白，避免浏览器生成过大的图片
+func (o Options) ValidateRegion() error {
+	scale := o.scaleFactor()
+	if o.Clip != nil {
+		if err := o.Clip.checkSize(scale); err != nil {
+			return err
+		}
+	}
+	if float64(2*o.Padding)*scale > maxImageSide {
+		return fmt.Errorf("留白 %d 过大，两侧留白乘以像素比后不能超过 %d 像素", o.Padding, maxImageSide)
+	}
+	return nil
+}
+
+// DefaultMaxElements 截取所有匹配元素时默认的元素数上限
+const DefaultMaxElements = 50
+
+// ErrTooManyElements 匹配的元素数超过 Options.MaxElements
+var ErrTooManyElements = errors.New("匹配的元素过多")
+
+// Shot 一次会话中截取的多张图片之一
+type Shot struct {
+	Name  string
+	Image []byte
+}
+
+// elementRectsJS 返回匹配选择器的所有元素相对于页面的位置
+const elementRectsJS = `(sel => Array.from(document.querySelectorAll(sel)).map(el => {
+	const r = el.getBoundingClientRect();
+	return {x: r.left + window.scrollX, y: r.top + window.scrollY, width: r.width, height: r.height};
+}))(%s)`
+
+// elementRects 获取匹配选择器的所有可见元素的位置
+func elementRects(ctx context.Context, selector string) ([]Clip, error) {
+	sel, err := json.Marshal(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var rects []Clip
+	if err := chromedp.Evaluate(fmt.Sprintf(elementRectsJS, sel), &rects).Do(ctx); err != nil {
+		return nil, fmt.Errorf("查找元素失败: %w", err)
+	}
+
+	// 跳过不可见（宽或高为0）的元素
+	visible := rects[:0]
+	for _, rect := range rects {
+		if rect.Width > 0 && rect.Height > 0 {
+			visible = append(visible, rect)
+		}
+	}
+	return visible, nil
+}
+
+// locateJS 返回匹配选择器的所有元素在截图中的位置（图片像素），
+// 整页截图时相对于页面左上角，否则相对于视口
+const locateJS = `((sels, full) => sels.flatMap(sel => Array.from(document.querySelectorAll(sel))).map(el => {
+	const r = el.getBoundingClientRect(), dpr = window.devicePixelRatio;
+	const x = full ? r.left + window.scrollX : r.left, y = full ? r.top + window.scrollY : r.top;
+	return {x: x * dpr, y: y * dpr, width: r.width * dpr, height: r.height * dpr};
+}))(%s, %t)`
+
+// locateAction 截图前记录 Options.Locate 匹配的可见元素的位置
+func locateAction(result *ScreenshotResult, options Options) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		sels, err := json.Marshal(options.Locate)
+		if err != nil {
+			return err
+		}
+
+		var rects []Clip
+		if err := chromedp.Evaluate(fmt.Sprintf(locateJS, sels, options.FullPage), &rects).Do(ctx); err != nil {
+			return fmt.Errorf("查找元素失败: %w", err)
+		}
+		for _, rect := range rects {
+			if rect.Width > 0 && rect.Height > 0 {
+				result.Regions = append(result.Regions, rect)
+			}
+		}
+		return nil
+	})
+}
+
+// captureClip 截取页面上的指定区域，区域过大时返回错误
+func captureClip(ctx context.Context, clip Clip, options Options) ([]byte, error) {
+	if err := clip.checkSize(options.scaleFactor()); err != nil {
+		return nil, err
+	}
+	return screenshotParams(options).
+		WithCaptureBeyondViewport(true).
+		WithClip(&page.Viewport{
+			X:      clip.X,
+			Y:      clip.Y,
+			Width:  clip.Width,
+			Height: clip.Height,
+			Scale:  1,
+		}).
+		Do(ctx)
+}
+
+// pad 在区域四周增加留白，不超出页面左上边界
+func (c Clip) pad(padding int) Clip {
+	p := float64(padding)
+	x, y := c.X-p, c.Y-p
+	if x < 0 {
+		x = 0
+	}
+	if y < 0 {
+		y = 0
+	}
+	return Clip{
+		X:      x,
+		Y:      y,
+		Width:  c.X + c.Width + p - x,
+		Height: c.Y + c.Height + p - y,
+	}
+}
+
+// regionCaptureAction 截取元素或指定区域，代替整页截图
+func regionCaptureAction(result *ScreenshotResult, options Options) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if options.Format == FormatPDF {
+			return errors.New("PDF格式不支持元素或区域截图")
+		}
+
+		if options.Clip != nil {
+			buf, err := captureClip(ctx, *options.Clip, options)
+			result.Image = buf
+			return err
+		}
+
+		rects, err := elementRects(ctx, options.Element)
+		if err != nil {
+			return err
+		}
+		if len(rects) == 0 {
+			return fmt.Errorf("未找到可见的元素: %s", options.Element)
+		}
+		if !options.AllElements {
+			rects = rects[:1]
+		}
+		maxElements := options.MaxElements
+		if maxElements <= 0 {
+			maxElements = DefaultMaxElements
+		}
+		if len(rects) > maxElements {
+			return fmt.Errorf("%w: %s 匹配了 %d 个可见元素，最多 %d 个", ErrTooManyElements, options.Element, len(rects), maxElements)
+		}
+
+		for i, rect := range rects {
+			buf, err := captureClip(ctx, rect.pad(options.Padding), options)
+			if err != nil {
+				return err
+			}
+			result.Shots = append(result.Shots, Shot{
+				Name:  fmt.Sprintf("element-%d.%s", i+1, options.Format.Extension()),
+				Image: buf,
+			})
+		}
+		result.Image = result.Shots[0].Image
+		return nil
+	})
+}
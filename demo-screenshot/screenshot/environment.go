@@ -0,0 +1,253 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// ColorScheme 页面的配色方案，对应CSS的 prefers-color-scheme
+type ColorScheme string
+
+const (
+	ColorSchemeLight ColorScheme = "light"
+	ColorSchemeDark  ColorScheme = "dark"
+)
+
+// ParseColorScheme 解析配色方案
+func ParseColorScheme(value string) (ColorScheme, error) {
+	switch scheme := ColorScheme(strings.ToLower(value)); scheme {
+	case ColorSchemeLight, ColorSchemeDark:
+		return scheme, nil
+	default:
+		return "", fmt.Errorf("不支持的配色方案: %s（可用: light, dark）", value)
+	}
+}
+
+// MediaType CSS媒体类型
+type MediaType string
+
+const (
+	MediaScreen MediaType = "screen"
+	MediaPrint  MediaType = "print"
+)
+
+// ParseMediaType 解析CSS媒体类型
+func ParseMediaType(value string) (MediaType, error) {
+	switch media := MediaType(strings.ToLower(value)); media {
+	case MediaScreen, MediaPrint:
+		return media, nil
+	default:
+		return "", fmt.Errorf("不支持的媒体类型: %s（可用: screen, print）", value)
+	}
+}
+
+// ParseLocale 检查语言标签（如 zh-CN、en），下划线转换为连字符
+func ParseLocale(value string) (string, error) {
+	locale := strings.ReplaceAll(strings.TrimSpace(value), "_", "-")
+	parts := strings.Split(locale, "-")
+	for i, part := range parts {
+		valid := len(part) >= 2 && len(part) <= 8
+		for _, c := range part {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+				valid = false
+			}
+		}
+		if !valid {
+			return "", fmt.Errorf("无效的语言: %s", value)
+		}
+	}
+	return locale, nil
+}
+
+// acceptLanguage 生成 Accept-Language 请求头，带地区的语言同时接受不带地区的语言，如 zh-CN,zh;q=0.9
+func acceptLanguage(locale string) string {
+	if base, _, ok := strings.Cut(locale, "-"); ok {
+		return locale + "," + base + ";q=0.9"
+	}
+	return locale
+}
+
+// Geolocation 模拟的地理位置
+type Geolocation struct {
+	Latitude  float64
+	Longitude float64
+	Accuracy  float64 // 精度（米）
+}
+
+// ParseGeolocation 解析 "纬度,经度" 或 "纬度,经度,精度" 形式的地理位置
+func ParseGeolocation(value string) (*Geolocation, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 && len(parts) != 3 {
+		return nil, fmt.Errorf("无效的地理位置: %s（格式为 纬度,经度[,精度]）", value)
+	}
+	nums := make([]float64, len(parts))
+	for i, part := range parts {
+		n, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的地理位置: %s", value)
+		}
+		nums[i] = n
+	}
+	geo := &Geolocation{Latitude: nums[0], Longitude: nums[1], Accuracy: 100}
+	if len(nums) == 3 {
+		geo.Accuracy = nums[2]
+	}
+	if geo.Latitude < -90 || geo.Latitude > 90 || geo.Longitude < -180 || geo.Longitude > 180 || geo.Accuracy < 0 {
+		return nil, fmt.Errorf("地理位置超出范围: %s", value)
+	}
+	return geo, nil
+}
+
+// usesEnvironment 是否需要设置渲染环境
+func (o Options) usesEnvironment() bool {
+	return o.ColorScheme != "" || o.ReducedMotion || o.Media != "" || o.Locale != "" ||
+		o.Timezone != "" || o.Geolocation != nil || o.DisableAnimations
+}
+
+// disableAnimationsCSS 让CSS动画和过渡立即结束，隐藏输入框的光标
+const disableAnimationsCSS = `*, *::before, *::after {
+	animation-delay: 0s !important;
+	animation-duration: 0s !important;
+	animation-iteration-count: 1 !important;
+	transition-delay: 0s !important;
+	transition-duration: 0s !important;
+	scroll-behavior: auto !important;
+	caret-color: transparent !important;
+}`
+
+// disableAnimationsJS 在文档创建时插入样式，脚本执行时 documentElement 可能还不存在
+const disableAnimationsJS = `(() => {
+	const add = () => {
+		const style = document.createElement('style');
+		style.textContent = ` + "`" + disableAnimationsCSS + "`" + `;
+		(document.head || document.documentElement).appendChild(style);
+	};
+	if (document.documentElement) {
+		add();
+	} else {
+		new MutationObserver((_, observer) => {
+			if (document.documentElement) {
+				observer.disconnect();
+				add();
+			}
+		}).observe(document, {childList: true});
+	}
+})()`
+
+// finishAnimationsJS 结束页面上剩余的动画（如Web Animations API创建的动画），无限循环的动画无法结束时取消
+const finishAnimationsJS = `document.getAnimations().forEach(a => {
+	try { a.finish(); } catch (e) { a.cancel(); }
+})`
+
+// environmentAction 设置配色方案、媒体类型、语言、时区和地理位置，在导航前执行
+func environmentAction(pageURL string, options Options) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var features []*emulation.MediaFeature
+		if options.ColorScheme != "" {
+			features = append(features, &emulation.MediaFeature{Name: "prefers-color-scheme", Value: string(options.ColorScheme)})
+		}
+		if options.ReducedMotion {
+			features = append(features, &emulation.MediaFeature{Name: "prefers-reduced-motion", Value: "reduce"})
+		}
+		if options.Media != "" || len(features) > 0 {
+			if err := emulation.SetEmulatedMedia().WithMedia(string(options.Media)).WithFeatures(features).Do(ctx); err != nil {
+				return fmt.Errorf("设置媒体类型失败: %w", err)
+			}
+		}
+
+		if options.Locale != "" {
+			if err := emulation.SetLocaleOverride().WithLocale(options.Locale).Do(ctx); err != nil {
+				return fmt.Errorf("设置语言失败: %w", err)
+			}
+		}
+
+		if options.Timezone != "" {
+			if err := emulation.SetTimezoneOverride(options.Timezone).Do(ctx); err != nil {
+				return fmt.Errorf("设置时区失败: %w", err)
+			}
+		}
+
+		if geo := options.Geolocation; geo != nil {
+			if err := grantGeolocation(ctx, pageURL); err != nil {
+				return err
+			}
+			err := emulation.SetGeolocationOverride().
+				WithLatitude(geo.Latitude).
+				WithLongitude(geo.Longitude).
+				WithAccuracy(geo.Accuracy).
+				Do(ctx)
+			if err != nil {
+				return fmt.Errorf("设置地理位置失败: %w", err)
+			}
+		}
+
+		if options.DisableAnimations {
+			if _, err := page.AddScriptToEvaluateOnNewDocument(disableAnimationsJS).Do(ctx); err != nil {
+				return fmt.Errorf("禁用动画失败: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// grantGeolocation 允许截图URL所在的源读取地理位置，权限属于浏览器，需要在浏览器上执行
+func grantGeolocation(ctx context.Context, pageURL string) error {
+	origin, err := urlOrigin(pageURL)
+	if err != nil {
+		return err
+	}
+	c := chromedp.FromContext(ctx)
+	grant := browser.GrantPermissions([]browser.PermissionType{browser.PermissionTypeGeolocation}).WithOrigin(origin)
+	if c.BrowserContextID != "" {
+		grant = grant.WithBrowserContextID(c.BrowserContextID)
+	}
+	if err := grant.Do(cdp.WithExecutor(ctx, c.Browser)); err != nil {
+		return fmt.Errorf("授予地理位置权限失败: %w", err)
+	}
+	return nil
+}
+
+// finishAnimationsAction 截图前结束页面上剩余的动画
+func finishAnimationsAction() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		_, exp, err := runtime.Evaluate(finishAnimationsJS).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("结束动画失败: %w", err)
+		}
+		if exp != nil {
+			return fmt.Errorf("结束动画失败: %s", exp.Text)
+		}
+		return nil
+	})
+}
+
+// userAgentAction 设置User-Agent和 Accept-Language，只指定语言时沿用浏览器默认的User-Agent
+func userAgentAction(userAgent, platform, locale string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if userAgent == "" {
+			c := chromedp.FromContext(ctx)
+			_, _, _, ua, _, err := browser.GetVersion().Do(cdp.WithExecutor(ctx, c.Browser))
+			if err != nil {
+				return fmt.Errorf("读取User-Agent失败: %w", err)
+			}
+			userAgent = ua
+		}
+		params := emulation.SetUserAgentOverride(userAgent)
+		if platform != "" {
+			params = params.WithPlatform(platform)
+		}
+		if locale != "" {
+			params = params.WithAcceptLanguage(acceptLanguage(locale))
+		}
+		return params.Do(ctx)
+	})
+}
@@ -0,0 +1,182 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// Format 截图的输出格式
+type Format string
+
+const (
+	FormatPNG  Format = "png"
+	FormatJPEG Format = "jpeg"
+	FormatWebP Format = "webp"
+	FormatPDF  Format = "pdf"
+)
+
+// ParseFormat 解析输出格式名称，不区分大小写
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "", "png":
+		return FormatPNG, nil
+	case "jpeg", "jpg":
+		return FormatJPEG, nil
+	case "webp":
+		return FormatWebP, nil
+	case "pdf":
+		return FormatPDF, nil
+	default:
+		return "", fmt.Errorf("不支持的输出格式: %s", name)
+	}
+}
+
+// ContentType 返回格式对应的MIME类型
+func (f Format) ContentType() string {
+	switch f {
+	case FormatJPEG:
+		return "image/jpeg"
+	case FormatWebP:
+		return "image/webp"
+	case FormatPDF:
+		return "application/pdf"
+	default:
+		return "image/png"
+	}
+}
+
+// Extension 返回格式对应的文件扩展名（不含点）
+func (f Format) Extension() string {
+	switch f {
+	case FormatJPEG:
+		return "jpg"
+	case FormatWebP, FormatPDF:
+		return string(f)
+	default:
+		return "png"
+	}
+}
+
+// PDFOptions PDF输出的配置，尺寸单位为英寸
+type PDFOptions struct {
+	PaperWidth      float64
+	PaperHeight     float64
+	MarginTop       float64
+	MarginBottom    float64
+	MarginLeft      float64
+	MarginRight     float64
+	Landscape       bool
+	PrintBackground bool   // 是否打印背景图形
+	HeaderTemplate  string // 页眉HTML模板，支持 date、title、url、pageNumber、totalPages 等class
+	FooterTemplate  string // 页脚HTML模板
+}
+
+// PaperSizes 常用纸张尺寸（英寸）
+var PaperSizes = map[string][2]float64{
+	"a3":      {11.69, 16.54},
+	"a4":      {8.27, 11.69},
+	"a5":      {5.83, 8.27},
+	"letter":  {8.5, 11},
+	"legal":   {8.5, 14},
+	"tabloid": {11, 17},
+}
+
+// DefaultPDFOptions 返回默认的PDF配置（A4，0.4英寸边距）
+func DefaultPDFOptions() PDFOptions {
+	a4 := PaperSizes["a4"]
+	return PDFOptions{
+		PaperWidth:      a4[0],
+		PaperHeight:     a4[1],
+		MarginTop:       0.4,
+		MarginBottom:    0.4,
+		MarginLeft:      0.4,
+		MarginRight:     0.4,
+		PrintBackground: true,
+	}
+}
+
+// SetPaper 按名称设置纸张尺寸
+func (o *PDFOptions) SetPaper(name string) error {
+	size, ok := PaperSizes[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("不支持的纸张尺寸: %s", name)
+	}
+	o.PaperWidth, o.PaperHeight = size[0], size[1]
+	return nil
+}
+
+// captureAction 按输出格式截图或生成PDF
+func captureAction(res *[]byte, options Options) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		if options.Format == FormatPDF {
+			*res, _, err = printToPDF(options.PDF).Do(ctx)
+			return err
+		}
+
+		if options.FullPage {
+			// 按页面尺寸截取，超过最大高度的部分不截取
+			clip, err := fullPageClip(ctx, options)
+			if err != nil {
+				return err
+			}
+			*res, err = captureClip(ctx, clip, options)
+			return err
+		}
+
+		*res, err = screenshotParams(options).Do(ctx)
+		return err
+	})
+}
+
+// screenshotParams 根据输出格式和质量生成 page.CaptureScreenshot 参数
+func screenshotParams(options Options) *page.CaptureScreenshotParams {
+	format := page.CaptureScreenshotFormatPng
+	switch options.Format {
+	case FormatJPEG:
+		format = page.CaptureScreenshotFormatJpeg
+	case FormatWebP:
+		format = page.CaptureScreenshotFormatWebp
+	}
+
+	params := page.CaptureScreenshot().
+		WithFromSurface(true).
+		WithFormat(format)
+	if format != page.CaptureScreenshotFormatPng && options.Quality > 0 {
+		params = params.WithQuality(int64(options.Quality))
+	}
+	return params
+}
+
+// printToPDF 根据配置生成 page.PrintToPDF 参数
+func printToPDF(o PDFOptions) *page.PrintToPDFParams {
+	params := page.PrintToPDF().
+		WithLandscape(o.Landscape).
+		WithPrintBackground(o.PrintBackground).
+		WithMarginTop(o.MarginTop).
+		WithMarginBottom(o.MarginBottom).
+		WithMarginLeft(o.MarginLeft).
+		WithMarginRight(o.MarginRight)
+	if o.PaperWidth > 0 && o.PaperHeight > 0 {
+		params = params.WithPaperWidth(o.PaperWidth).WithPaperHeight(o.PaperHeight)
+	}
+	if o.HeaderTemplate != "" || o.FooterTemplate != "" {
+		// 只设置其中一个时，另一个使用空模板，避免显示Chrome的默认页眉页脚
+		header, footer := o.HeaderTemplate, o.FooterTemplate
+		if header == "" {
+			header = "<span></span>"
+		}
+		if footer == "" {
+			footer = "<span></span>"
+		}
+		params = params.
+			WithDisplayHeaderFooter(true).
+			WithHeaderTemplate(header).
+			WithFooterTemplate(footer)
+	}
+	return params
+}
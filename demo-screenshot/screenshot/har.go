@@ -0,0 +1,274 @@
+package screenshot
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+)
+
+// HAR HTTP Archive 1.2 格式的网络请求记录，可以导入浏览器开发者工具查看
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog HAR的根对象
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Pages   []HARPage  `json:"pages"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator 生成HAR的工具
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HARPage 页面信息，时间单位为毫秒
+type HARPage struct {
+	StartedDateTime time.Time      `json:"startedDateTime"`
+	ID              string         `json:"id"`
+	Title           string         `json:"title"`
+	PageTimings     HARPageTimings `json:"pageTimings"`
+}
+
+// HARPageTimings 页面的DOMContentLoaded和load时间，-1表示未触发
+type HARPageTimings struct {
+	OnContentLoad float64 `json:"onContentLoad"`
+	OnLoad        float64 `json:"onLoad"`
+}
+
+// HAREntry 一个网络请求
+type HAREntry struct {
+	PageRef         string      `json:"pageref"`
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	ResourceType    string      `json:"_resourceType,omitempty"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+// HARRequest 请求信息
+type HARRequest struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Headers     []HARHeader `json:"headers"`
+	QueryString []HARHeader `json:"queryString"`
+	Cookies     []HARHeader `json:"cookies"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int         `json:"bodySize"`
+}
+
+// HARResponse 响应信息，请求失败时Status为0
+type HARResponse struct {
+	Status      int64       `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Headers     []HARHeader `json:"headers"`
+	Cookies     []HARHeader `json:"cookies"`
+	Content     HARContent  `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int64       `json:"bodySize"`
+}
+
+// HARHeader 名称和值
+type HARHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARContent 响应内容，不包含响应体
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+// HARTimings 请求各阶段的耗时（毫秒），-1表示不适用
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// harEntry 收集中的请求，响应和结束时间随事件陆续到达
+type harEntry struct {
+	started      time.Time // 墙上时间
+	startedMono  float64   // 单调时间（秒），和其他事件的时间戳比较
+	finishedMono float64
+	request      *network.Request
+	response     *network.Response
+	resourceType network.ResourceType
+	size         int64
+	err          string
+}
+
+// toHAR 转换为HAR格式
+func (e *harEntry) toHAR(pageRef string) HAREntry {
+	entry := HAREntry{
+		PageRef:         pageRef,
+		StartedDateTime: e.started,
+		Request: HARRequest{
+			Method:      e.request.Method,
+			URL:         e.request.URL,
+			HTTPVersion: "HTTP/1.1",
+			Headers:     harHeaders(e.request.Headers),
+			QueryString: harQuery(e.request.URL),
+			Cookies:     []HARHeader{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Response: HARResponse{
+			HTTPVersion: "HTTP/1.1",
+			Headers:     []HARHeader{},
+			Cookies:     []HARHeader{},
+			Content:     HARContent{Size: e.size},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Timings:      HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1},
+		ResourceType: string(e.resourceType),
+		Error:        e.err,
+	}
+
+	if e.finishedMono > 0 {
+		entry.Time = (e.finishedMono - e.startedMono) * 1000
+	}
+
+	if r := e.response; r != nil {
+		entry.Response.Status = r.Status
+		entry.Response.StatusText = r.StatusText
+		entry.Response.HTTPVersion = httpVersion(r.Protocol)
+		entry.Request.HTTPVersion = entry.Response.HTTPVersion
+		entry.Response.Headers = harHeaders(r.Headers)
+		entry.Response.Content.MimeType = r.MimeType
+		for name, value := range r.Headers {
+			if strings.EqualFold(name, "Location") {
+				entry.Response.RedirectURL = fmt.Sprint(value)
+			}
+		}
+		if e.size > 0 {
+			entry.Response.BodySize = e.size
+		}
+		entry.ServerIPAddress = r.RemoteIPAddress
+		if r.Timing != nil {
+			entry.Timings = harTimings(r.Timing, e.startedMono, entry.Time)
+		}
+	}
+	return entry
+}
+
+// harTimings 根据 ResourceTiming 计算各阶段耗时，ResourceTiming 中的时间是相对于 RequestTime 的毫秒数
+func harTimings(t *network.ResourceTiming, startedMono float64, total float64) HARTimings {
+	span := func(start, end float64) float64 {
+		if start < 0 || end < 0 {
+			return -1
+		}
+		return end - start
+	}
+
+	timings := HARTimings{
+		DNS:     span(t.DNSStart, t.DNSEnd),
+		Connect: span(t.ConnectStart, t.ConnectEnd), // HAR规定 connect 包含 ssl
+		SSL:     span(t.SslStart, t.SslEnd),
+		Send:    max(t.SendEnd-t.SendStart, 0),
+		Wait:    max(t.ReceiveHeadersEnd-t.SendEnd, 0),
+	}
+
+	// 请求发出到开始建立连接之间的排队时间
+	queued := (t.RequestTime - startedMono) * 1000
+	firstStart := t.SendStart
+	for _, start := range []float64{t.ConnectStart, t.DNSStart} {
+		if start >= 0 {
+			firstStart = start
+		}
+	}
+	timings.Blocked = max(queued+firstStart, 0)
+
+	// 剩余的时间为接收响应体的时间
+	if total > 0 {
+		timings.Receive = max(total-timings.Blocked-max(timings.DNS, 0)-max(timings.Connect, 0)-timings.Send-timings.Wait, 0)
+	}
+	return timings
+}
+
+// buildHAR 按开始时间排序生成HAR
+func buildHAR(page HARPage, entries []*harEntry) *HAR {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].startedMono < entries[j].startedMono
+	})
+
+	har := &HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "demo-screenshot", Version: "1.0"},
+		Pages:   []HARPage{page},
+		Entries: make([]HAREntry, 0, len(entries)),
+	}}
+	for _, e := range entries {
+		har.Log.Entries = append(har.Log.Entries, e.toHAR(page.ID))
+	}
+	return har
+}
+
+func harHeaders(headers network.Headers) []HARHeader {
+	list := make([]HARHeader, 0, len(headers))
+	for name, value := range headers {
+		list = append(list, HARHeader{Name: name, Value: fmt.Sprint(value)})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func harQuery(rawURL string) []HARHeader {
+	list := []HARHeader{}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return list
+	}
+	for name, values := range u.Query() {
+		for _, value := range values {
+			list = append(list, HARHeader{Name: name, Value: value})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// httpVersion 把 h2、http/1.1 等协议名称转换为HAR中的HTTP版本
+func httpVersion(protocol string) string {
+	switch protocol {
+	case "h2":
+		return "HTTP/2.0"
+	case "h3", "h3-29":
+		return "HTTP/3.0"
+	case "http/1.0":
+		return "HTTP/1.0"
+	case "":
+		return "HTTP/1.1"
+	default:
+		return protocol
+	}
+}
+
+// monoSeconds 把CDP事件的单调时间戳转换为秒，和 ResourceTiming.RequestTime 使用同一基准
+func monoSeconds(t *cdp.MonotonicTime) float64 {
+	if t == nil {
+		return 0
+	}
+	return t.Time().Sub(*cdp.MonotonicTimeEpoch).Seconds()
+}
@@ -0,0 +1,124 @@
+package screenshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// consentSelectors 常见的Cookie同意弹窗（CMP）的选择器，只隐藏不删除，避免CMP的脚本出错
+var consentSelectors = []string{
+	"#onetrust-consent-sdk",             // OneTrust
+	"#CybotCookiebotDialog",             // Cookiebot
+	"#CybotCookiebotDialogBodyUnderlay", // Cookiebot
+	".qc-cmp2-container",                // Quantcast
+	"#truste-consent-track",             // TrustArc
+	".truste_overlay",                   // TrustArc
+	".truste_box_overlay",               // TrustArc
+	"#didomi-host",                      // Didomi
+	"#usercentrics-root",                // Usercentrics
+	".osano-cm-window",                  // Osano
+	".cc-window",                        // Cookie Consent (Osano开源版)
+	".cc-banner",                        // Cookie Consent (Osano开源版)
+	".cmplz-cookiebanner",               // Complianz
+	"#iubenda-cs-banner",                // iubenda
+	".klaro",                            // Klaro
+	"#termly-code-snippet-support",      // Termly
+	".cky-consent-container",            // CookieYes
+	".cky-overlay",                      // CookieYes
+	"#BorlabsCookieBox",                 // Borlabs Cookie
+	"[id^='sp_message_container']",      // Sourcepoint
+	".fc-consent-root",                  // Google Funding Choices
+	"#cookie-notice",                    // Cookie Notice
+	"#moove_gdpr_cookie_info_bar",       // GDPR Cookie Compliance
+	"#cookie-law-info-bar",              // CookieLawInfo
+	".cli-modal-backdrop",               // CookieLawInfo
+}
+
+// consentCSS 同意弹窗打开时部分CMP会禁止页面滚动，隐藏弹窗后恢复
+const consentCSS = `html.sp-message-open, body.didomi-popup-open, body.cmplz-cookiebanner-open, body.qc-cmp-ui-showing {
+	overflow: auto !important;
+	position: static !important;
+}`
+
+// usesInjection 是否需要在截图前修改页面
+func (o Options) usesInjection() bool {
+	return o.CSS != "" || o.Script != "" || len(o.Hide) > 0 || len(o.Remove) > 0 || o.HideConsent
+}
+
+// injectJS 插入样式并删除元素，返回无效的选择器。每个选择器单独生成一条规则，
+// 无效的选择器不影响其他规则；样式对之后才出现的元素（如延迟加载的聊天窗口）同样生效
+const injectJS = `((css, hide, remove) => {
+	const invalid = [...hide, ...remove].filter(sel => {
+		try { document.querySelector(sel); return false; } catch (e) { return true; }
+	});
+	if (invalid.length > 0) {
+		return invalid;
+	}
+	const rules = [
+		css,
+		...hide.map(sel => sel + ' { visibility: hidden !important; }'),
+		...remove.map(sel => sel + ' { display: none !important; }'),
+	];
+	const style = document.createElement('style');
+	style.textContent = rules.join('\n');
+	(document.head || document.documentElement).appendChild(style);
+	remove.forEach(sel => document.querySelectorAll(sel).forEach(el => el.remove()));
+	return [];
+})(%s, %s, %s)`
+
+// injectAction 注入CSS，隐藏或删除元素，再执行注入的脚本。在页面加载完成后、截图前执行
+func injectAction(options Options) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		css := options.CSS
+		if options.HideConsent {
+			css += "\n" + consentRules()
+		}
+
+		if css != "" || len(options.Hide) > 0 || len(options.Remove) > 0 {
+			args := make([]any, 3)
+			for i, v := range []any{css, nonNil(options.Hide), nonNil(options.Remove)} {
+				data, err := json.Marshal(v)
+				if err != nil {
+					return err
+				}
+				args[i] = data
+			}
+			var invalid []string
+			if err := chromedp.Evaluate(fmt.Sprintf(injectJS, args...), &invalid).Do(ctx); err != nil {
+				return fmt.Errorf("注入样式失败: %w", err)
+			}
+			if len(invalid) > 0 {
+				return fmt.Errorf("无效的选择器: %s", strings.Join(invalid, ", "))
+			}
+		}
+
+		if options.Script != "" {
+			if err := chromedp.Evaluate(options.Script, nil, awaitPromise).Do(ctx); err != nil {
+				return fmt.Errorf("执行注入的脚本失败: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// consentRules 隐藏同意弹窗的样式
+func consentRules() string {
+	var b strings.Builder
+	for _, sel := range consentSelectors {
+		b.WriteString(sel + " { display: none !important; }\n")
+	}
+	b.WriteString(consentCSS)
+	return b.String()
+}
+
+// nonNil 把nil切片转换为空切片，序列化为 [] 而不是 null
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
@@ -0,0 +1,248 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// 可以按类型屏蔽的资源
+var resourceTypes = map[string]network.ResourceType{
+	"image":      network.ResourceTypeImage,
+	"script":     network.ResourceTypeScript,
+	"font":       network.ResourceTypeFont,
+	"media":      network.ResourceTypeMedia,
+	"stylesheet": network.ResourceTypeStylesheet,
+}
+
+// ParseResourceTypes 解析逗号分隔的资源类型列表：image,script,font,media,stylesheet
+func ParseResourceTypes(value string) ([]network.ResourceType, error) {
+	var types []network.ResourceType
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		t, ok := resourceTypes[name]
+		if !ok {
+			return nil, fmt.Errorf("未知的资源类型: %s", name)
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// interceptor 通过Fetch域拦截页面发出的请求，按URL策略、资源类型和屏蔽列表决定是否放行，
+// 并向截图URL所在的源提供附加的请求头和HTTP基本认证，不会泄露给CDN、统计脚本等第三方。
+// 和按URL后缀屏蔽不同，没有扩展名的CDN图片和脚本也能按类型识别。
+// chromedp 默认关闭了 site-per-process，跨域iframe的请求同样会被拦截
+type interceptor struct {
+	types     map[network.ResourceType]bool
+	blocklist *Blocklist
+	policy    *URLPolicy
+	auth      *BasicAuth
+	headers   map[string]string
+	origin    string // 只向该源提供请求头和基本认证信息
+
+	mu              sync.Mutex
+	blockedByType   int
+	blockedByList   int
+	blockedByPolicy int
+	policyChecks    map[string]error // 按协议和主机缓存的策略检查结果
+	policyErr       error            // 页面导航（包括重定向）被策略拒绝的原因
+	authAttempts    map[fetch.RequestID]bool
+}
+
+// newInterceptor 根据选项创建拦截器，不需要拦截时返回nil
+func newInterceptor(pageURL string, options Options) *interceptor {
+	types := make(map[network.ResourceType]bool)
+	for _, t := range options.BlockResources {
+		types[t] = true
+	}
+	if options.BlockImages {
+		types[network.ResourceTypeImage] = true
+	}
+	if options.BlockJS {
+		types[network.ResourceTypeScript] = true
+	}
+
+	if len(types) == 0 && options.Blocklist == nil && options.BasicAuth == nil && options.URLPolicy == nil && len(options.Headers) == 0 {
+		return nil
+	}
+
+	i := &interceptor{
+		types:        types,
+		blocklist:    options.Blocklist,
+		policy:       options.URLPolicy,
+		auth:         options.BasicAuth,
+		headers:      options.Headers,
+		policyChecks: make(map[string]error),
+		authAttempts: make(map[fetch.RequestID]bool),
+	}
+	i.origin, _ = urlOrigin(pageURL)
+	return i
+}
+
+// enableAction 开始监听并启用请求拦截，需要在导航前执行
+func (i *interceptor) enableAction(taskCtx context.Context) chromedp.Action {
+	chromedp.ListenTarget(taskCtx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *fetch.EventRequestPaused:
+			go i.handle(taskCtx, ev)
+		case *fetch.EventAuthRequired:
+			go i.handleAuth(taskCtx, ev)
+		}
+	})
+	return fetch.Enable().WithHandleAuthRequests(i.auth != nil)
+}
+
+// handle 放行或屏蔽一个被暂停的请求
+func (i *interceptor) handle(taskCtx context.Context, ev *fetch.EventRequestPaused) {
+	var action chromedp.Action = fetch.ContinueRequest(ev.RequestID)
+	if len(i.headers) > 0 {
+		if origin, err := urlOrigin(ev.Request.URL); err == nil && origin == i.origin {
+			action = fetch.ContinueRequest(ev.RequestID).WithHeaders(mergeHeaders(ev.Request.Headers, i.headers))
+		}
+	}
+	if !i.allowed(taskCtx, ev) {
+		action = fetch.FailRequest(ev.RequestID, network.ErrorReasonAccessDenied)
+	} else if i.block(ev) {
+		action = fetch.FailRequest(ev.RequestID, network.ErrorReasonBlockedByClient)
+	}
+	// 页面关闭后请求会被丢弃，忽略错误
+	_ = chromedp.Run(taskCtx, action)
+}
+
+// mergeHeaders 在原有的请求头上附加请求头，同名（不区分大小写）的请求头被覆盖
+func mergeHeaders(current network.Headers, extra map[string]string) []*fetch.HeaderEntry {
+	entries := make([]*fetch.HeaderEntry, 0, len(current)+len(extra))
+	for name, value := range current {
+		if hasFold(extra, name) {
+			continue
+		}
+		entries = append(entries, &fetch.HeaderEntry{Name: name, Value: fmt.Sprint(value)})
+	}
+	for name, value := range extra {
+		entries = append(entries, &fetch.HeaderEntry{Name: name, Value: value})
+	}
+	return entries
+}
+
+func hasFold(m map[string]string, key string) bool {
+	for k := range m {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleAuth 响应HTTP认证质询，每个请求只提供一次凭据，避免密码错误时反复重试
+func (i *interceptor) handleAuth(taskCtx context.Context, ev *fetch.EventAuthRequired) {
+	response := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseCancelAuth}
+
+	i.mu.Lock()
+	attempted := i.authAttempts[ev.RequestID]
+	i.authAttempts[ev.RequestID] = true
+	i.mu.Unlock()
+
+	if i.auth != nil && !attempted && strings.EqualFold(ev.AuthChallenge.Origin, i.origin) {
+		response = &fetch.AuthChallengeResponse{
+			Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+			Username: i.auth.Username,
+			Password: i.auth.Password,
+		}
+	}
+	_ = chromedp.Run(taskCtx, fetch.ContinueWithAuth(ev.RequestID, response))
+}
+
+// allowed 按URL策略检查请求，同一协议和主机只检查一次
+func (i *interceptor) allowed(ctx context.Context, ev *fetch.EventRequestPaused) bool {
+	if i.policy == nil {
+		return true
+	}
+
+	key := ev.Request.URL
+	if u, err := url.Parse(ev.Request.URL); err == nil {
+		key = u.Scheme + "://" + u.Host
+	}
+	i.mu.Lock()
+	err, checked := i.policyChecks[key]
+	i.mu.Unlock()
+
+	if !checked {
+		// 解析主机名时不持有锁
+		err = i.policy.check(ctx, ev.Request.URL, true)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.policyChecks[key] = err
+	if err == nil {
+		return true
+	}
+	i.blockedByPolicy++
+	if ev.ResourceType == network.ResourceTypeDocument && i.policyErr == nil {
+		i.policyErr = err
+	}
+	return false
+}
+
+// blockSchemesAction 屏蔽指定协议的所有请求，用于不经过Fetch拦截的WebSocket
+func blockSchemesAction(schemes []string) chromedp.Action {
+	patterns := make([]string, 0, len(schemes))
+	for _, scheme := range schemes {
+		patterns = append(patterns, scheme+"://*")
+	}
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if err := network.Enable().Do(ctx); err != nil {
+			return err
+		}
+		if err := network.SetBlockedURLs(patterns).Do(ctx); err != nil {
+			return fmt.Errorf("屏蔽WebSocket失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// navigationError 返回页面导航被URL策略拒绝的原因
+func (i *interceptor) navigationError() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.policyErr
+}
+
+// block 判断是否屏蔽请求并计数，页面本身的文档请求不会被屏蔽
+func (i *interceptor) block(ev *fetch.EventRequestPaused) bool {
+	if ev.ResourceType == network.ResourceTypeDocument {
+		return false
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.types[ev.ResourceType] {
+		i.blockedByType++
+		return true
+	}
+	if i.blocklist != nil && i.blocklist.Blocks(ev.Request.URL) {
+		i.blockedByList++
+		return true
+	}
+	return false
+}
+
+// report 把屏蔽的请求数写入耗时信息
+func (i *interceptor) report(timing *TimingInfo) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	timing.BlockedResources = i.blockedByType
+	timing.BlockedByList = i.blockedByList
+	timing.BlockedByPolicy = i.blockedByPolicy
+}
@@ -0,0 +1,214 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// URLPolicy 限制可以截图的URL，防止通过截图服务访问内网（SSRF）。
+// 页面导航、重定向和子资源请求都会按策略检查；浏览器的连接经过按策略拨号的代理，
+// 只连接检查过的地址，检查后DNS结果变化（DNS重绑定）也无法访问内网
+type URLPolicy struct {
+	AllowedSchemes []string // 允许的协议，默认为 http 和 https
+	AllowPrivate   bool     // 允许访问内网、回环和链路本地地址
+	AllowHosts     []string // 只允许访问这些主机及其子域名，为空表示不限制
+	DenyHosts      []string // 禁止访问这些主机及其子域名
+
+	// Resolver 解析主机名，为空时使用 net.DefaultResolver
+	Resolver interface {
+		LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+	}
+}
+
+// DefaultURLPolicy 返回默认策略：只允许 http/https，禁止访问内网地址
+func DefaultURLPolicy() *URLPolicy {
+	return &URLPolicy{AllowedSchemes: []string{"http", "https"}}
+}
+
+// 不经过网络的协议，子资源使用时不检查
+var localSchemes = map[string]bool{
+	"data":  true,
+	"blob":  true,
+	"about": true,
+}
+
+// 除 netip 已识别的地址外，不允许访问的保留地址段
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // 本网络
+	netip.MustParsePrefix("100.64.0.0/10"), // 运营商级NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF协议分配
+	netip.MustParsePrefix("198.18.0.0/15"), // 基准测试
+	netip.MustParsePrefix("240.0.0.0/4"),   // 保留
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64，可能映射到内网IPv4地址
+}
+
+// 主机名解析的超时时间
+const resolveTimeout = 5 * time.Second
+
+// Check 检查页面URL是否允许访问
+func (p *URLPolicy) Check(ctx context.Context, rawURL string) error {
+	return p.check(ctx, rawURL, false)
+}
+
+// check 检查URL，subresource 表示页面发出的子资源请求
+func (p *URLPolicy) check(ctx context.Context, rawURL string, subresource bool) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("无效的URL: %s", rawURL)
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if subresource && localSchemes[scheme] {
+		return nil
+	}
+	if !p.schemeAllowed(scheme) {
+		return fmt.Errorf("不允许的协议: %s", u.Scheme)
+	}
+
+	if u.Hostname() == "" {
+		return fmt.Errorf("URL缺少主机名: %s", rawURL)
+	}
+	_, err = p.checkHost(ctx, u.Hostname())
+	return err
+}
+
+// checkHost 按主机列表检查主机，不允许访问内网时解析主机名并检查所有地址，返回检查过的地址
+func (p *URLPolicy) checkHost(ctx context.Context, host string) ([]netip.Addr, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" {
+		return nil, fmt.Errorf("缺少主机名")
+	}
+	if matchHost(host, p.DenyHosts) {
+		return nil, fmt.Errorf("禁止访问的主机: %s", host)
+	}
+	if len(p.AllowHosts) > 0 && !matchHost(host, p.AllowHosts) {
+		return nil, fmt.Errorf("不在允许列表中的主机: %s", host)
+	}
+	if p.AllowPrivate {
+		return nil, nil
+	}
+
+	addrs, err := p.resolve(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("解析主机 %s 失败: %w", host, err)
+	}
+	// 任何一个地址是内网地址都拒绝，避免浏览器选择了其中的内网地址
+	for _, addr := range addrs {
+		if isPrivateAddr(addr) {
+			return nil, fmt.Errorf("禁止访问内网地址: %s (%s)", host, addr)
+		}
+	}
+	return addrs, nil
+}
+
+// DialContext 按策略检查主机后只连接检查过的地址，不会在连接时重新解析主机名。
+// 可用作 http.Transport 的 DialContext，浏览器代理和任务回调都通过它连接
+func (p *URLPolicy) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := p.checkHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var d net.Dialer
+	if addrs == nil {
+		// 允许访问内网时不限制地址
+		return d.DialContext(ctx, network, address)
+	}
+	var lastErr error
+	for _, addr := range addrs {
+		conn, err := d.DialContext(ctx, network, net.JoinHostPort(addr.Unmap().String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// blockedSchemes 返回不在允许列表中的WebSocket协议。WebSocket请求不经过Fetch拦截，需要单独屏蔽
+func (p *URLPolicy) blockedSchemes() []string {
+	var blocked []string
+	for _, scheme := range []string{"ws", "wss"} {
+		if !p.schemeAllowed(scheme) {
+			blocked = append(blocked, scheme)
+		}
+	}
+	return blocked
+}
+
+func (p *URLPolicy) schemeAllowed(scheme string) bool {
+	schemes := p.AllowedSchemes
+	if len(schemes) == 0 {
+		schemes = []string{"http", "https"}
+	}
+	for _, s := range schemes {
+		if strings.EqualFold(s, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve 解析主机名，IP地址直接返回
+func (p *URLPolicy) resolve(ctx context.Context, host string) ([]netip.Addr, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{addr}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+
+	var resolver interface {
+		LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+	} = net.DefaultResolver
+	if p.Resolver != nil {
+		resolver = p.Resolver
+	}
+	addrs, err := resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("没有找到地址")
+	}
+	return addrs, nil
+}
+
+// isPrivateAddr 判断地址是否为内网、回环、链路本地或其他保留地址
+func isPrivateAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() || addr.IsUnspecified() {
+		return true
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchHost 判断主机是否为列表中的主机或其子域名
+func matchHost(host string, hosts []string) bool {
+	for _, h := range hosts {
+		h = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(h), "*."))
+		if h == "" {
+			continue
+		}
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,330 @@
+package screenshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// PoolOptions 浏览器池的配置选项
+type PoolOptions struct {
+	Browsers       int // 常驻的浏览器进程数
+	MaxConcurrency int // 同时进行的截图数量上限
+	MaxCaptures    int // 每个浏览器完成多少次截图后重启，0表示不限制
+
+	DisableWebSecurity bool // 关闭浏览器的同源策略
+
+	// URLPolicy 不为空时浏览器的所有连接经过按策略拨号的代理，防止DNS重绑定和WebSocket访问内网。
+	// 截图请求的 Options.URLPolicy 应使用同一个策略
+	URLPolicy *URLPolicy
+}
+
+// DefaultPoolOptions 返回默认的浏览器池配置
+func DefaultPoolOptions() PoolOptions {
+	return PoolOptions{
+		Browsers:       2,
+		MaxConcurrency: 8,
+		MaxCaptures:    200,
+	}
+}
+
+// Pool 保持若干浏览器进程常驻，每次截图在独立的隐身上下文中打开新标签页，
+// 避免每次截图都启动浏览器
+type Pool struct {
+	options PoolOptions
+	sem     chan struct{}
+
+	mu      sync.Mutex
+	slots   []*poolSlot
+	next    int
+	closed  bool
+	changed chan struct{} // 替换完成时关闭，通知等待可用浏览器的请求
+	proxy   *policyProxy
+}
+
+// poolSlot 浏览器池中的一个位置，替换浏览器时在锁外启动新进程
+type poolSlot struct {
+	browser   *pooledBrowser
+	replacing bool      // 正在启动替换的浏览器
+	failures  int       // 连续启动失败的次数
+	retryAt   time.Time // 启动失败后，在此之前不再重试
+}
+
+// 浏览器启动失败后重试的最短和最长间隔
+const (
+	minRestartBackoff = time.Second
+	maxRestartBackoff = 30 * time.Second
+)
+
+// pooledBrowser 浏览器池中的一个浏览器进程
+type pooledBrowser struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	browser  *chromedp.Browser
+	captures int  // 已分配的截图次数
+	active   int  // 正在使用的标签页数
+	retiring bool // 已被替换，标签页全部关闭后退出
+}
+
+// NewPool 创建浏览器池并启动所有浏览器
+func NewPool(options PoolOptions) (*Pool, error) {
+	if options.Browsers <= 0 {
+		options.Browsers = 1
+	}
+	if options.MaxConcurrency <= 0 {
+		options.MaxConcurrency = options.Browsers
+	}
+
+	p := &Pool{
+		options: options,
+		sem:     make(chan struct{}, options.MaxConcurrency),
+		changed: make(chan struct{}),
+	}
+
+	proxyURL := ""
+	if options.URLPolicy != nil {
+		proxy, err := startPolicyProxy(options.URLPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("启动代理失败: %w", err)
+		}
+		p.proxy = proxy
+		proxyURL = proxy.url()
+	}
+
+	for i := 0; i < options.Browsers; i++ {
+		b, err := startBrowser(options, proxyURL)
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("启动浏览器失败: %w", err)
+		}
+		p.slots = append(p.slots, &poolSlot{browser: b})
+	}
+	return p, nil
+}
+
+// startBrowser 启动一个浏览器进程并等待其就绪
+func startBrowser(options PoolOptions, proxyURL string) (*pooledBrowser, error) {
+	browserOptions := DefaultOptions()
+	browserOptions.DisableWebSecurity = options.DisableWebSecurity
+	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), allocatorOptions(browserOptions, proxyURL)...)
+	ctx, cancel := chromedp.NewContext(allocCtx)
+
+	// 第一次Run时才真正启动浏览器
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		allocCancel()
+		return nil, err
+	}
+
+	return &pooledBrowser{
+		ctx: ctx,
+		cancel: func() {
+			cancel()
+			allocCancel()
+		},
+		browser: chromedp.FromContext(ctx).Browser,
+	}, nil
+}
+
+func (p *Pool) proxyURL() string {
+	if p.proxy == nil {
+		return ""
+	}
+	return p.proxy.url()
+}
+
+// alive 检查浏览器进程是否仍然连接
+func (b *pooledBrowser) alive() bool {
+	select {
+	case <-b.browser.LostConnection:
+		return false
+	default:
+		return true
+	}
+}
+
+// Capture 使用池中的浏览器获取指定URL的网页截图
+func (p *Pool) Capture(url string, options Options) (*ScreenshotResult, error) {
+	return p.CaptureContext(context.Background(), url, options)
+}
+
+// CaptureContext 与 Capture 相同，ctx 取消时中止截图并返回包装了 ctx.Err() 的错误
+func (p *Pool) CaptureContext(ctx context.Context, url string, options Options) (*ScreenshotResult, error) {
+	result := &ScreenshotResult{
+		URL:       url,
+		Timestamp: time.Now(),
+		Timing: TimingInfo{
+			StartTime: time.Now(),
+		},
+	}
+
+	err := p.capture(ctx, url, options, result)
+	if err != nil && ctx.Err() != nil {
+		// 浏览器返回的错误不一定包装了取消的原因
+		err = fmt.Errorf("截图已取消: %w", ctx.Err())
+	}
+	if err != nil {
+		result.Error = err
+	}
+	return result, err
+}
+
+func (p *Pool) capture(parent context.Context, url string, options Options, result *ScreenshotResult) error {
+	if url == "" {
+		return errors.New("URL不能为空")
+	}
+
+	// 设置超时上下文，包括等待空闲标签页的时间
+	ctx, cancel := context.WithTimeout(parent, options.Timeout)
+	defer cancel()
+
+	select {
+	case p.sem <- struct{}{}:
+		defer func() { <-p.sem }()
+	case <-ctx.Done():
+		return fmt.Errorf("等待空闲浏览器超时: %w", ctx.Err())
+	}
+
+	b, err := p.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.release(b)
+
+	// 在独立的隐身上下文中打开标签页，标签页之间不共享Cookie和缓存
+	startBrowser := time.Now()
+	tabCtx, cancelTab := chromedp.NewContext(b.ctx, chromedp.WithNewBrowserContext())
+	defer cancelTab()
+
+	// 标签页的生命周期受截图超时限制
+	stop := context.AfterFunc(ctx, cancelTab)
+	defer stop()
+
+	if err := chromedp.Run(tabCtx); err != nil {
+		return fmt.Errorf("创建标签页失败: %w", err)
+	}
+	result.Timing.BrowserStart = time.Since(startBrowser)
+
+	return capture(tabCtx, url, options, result)
+}
+
+// acquire 选择正在使用的标签页最少的浏览器。已崩溃或达到截图次数上限的浏览器在后台替换，
+// 达到上限的浏览器在替换完成前继续使用；没有可用的浏览器时等待替换完成
+func (p *Pool) acquire(ctx context.Context) (*pooledBrowser, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, errors.New("浏览器池已关闭")
+		}
+
+		best := -1
+		for i := range p.slots {
+			idx := (p.next + i) % len(p.slots)
+			b := p.slots[idx].browser
+			if !b.alive() || p.options.MaxCaptures > 0 && b.captures >= p.options.MaxCaptures {
+				p.replace(idx)
+				if !b.alive() {
+					continue
+				}
+			}
+			if best < 0 || b.active < p.slots[best].browser.active {
+				best = idx
+			}
+		}
+		if best >= 0 {
+			p.next = (best + 1) % len(p.slots)
+			b := p.slots[best].browser
+			b.captures++
+			b.active++
+			p.mu.Unlock()
+			return b, nil
+		}
+		changed := p.changed
+		p.mu.Unlock()
+
+		// 启动失败的浏览器在退避结束后由下一次检查重新启动
+		timer := time.NewTimer(minRestartBackoff)
+		select {
+		case <-changed:
+			timer.Stop()
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("没有可用的浏览器: %w", ctx.Err())
+		}
+	}
+}
+
+// replace 在后台启动新的浏览器替换指定位置的浏览器，调用时需持有锁。
+// 旧浏览器在标签页全部关闭后退出，启动失败时按指数退避重试
+func (p *Pool) replace(idx int) {
+	slot := p.slots[idx]
+	if slot.replacing || time.Now().Before(slot.retryAt) {
+		return
+	}
+	slot.replacing = true
+
+	go func() {
+		nb, err := startBrowser(p.options, p.proxyURL())
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		slot.replacing = false
+		if p.closed {
+			if err == nil {
+				nb.cancel()
+			}
+			return
+		}
+		if err != nil {
+			slot.failures++
+			backoff := min(minRestartBackoff<<min(slot.failures-1, 5), maxRestartBackoff)
+			slot.retryAt = time.Now().Add(backoff)
+			return
+		}
+
+		old := slot.browser
+		old.retiring = true
+		if old.active == 0 || !old.alive() {
+			old.cancel()
+		}
+		slot.browser = nb
+		slot.failures = 0
+		slot.retryAt = time.Time{}
+
+		// 通知等待中的请求
+		close(p.changed)
+		p.changed = make(chan struct{})
+	}()
+}
+
+// release 归还浏览器
+func (p *Pool) release(b *pooledBrowser) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	b.active--
+	if b.retiring && b.active == 0 {
+		b.cancel()
+	}
+}
+
+// Close 关闭池中的所有浏览器
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	for _, slot := range p.slots {
+		slot.browser.cancel()
+	}
+	p.slots = nil
+	if p.proxy != nil {
+		p.proxy.Close()
+	}
+}
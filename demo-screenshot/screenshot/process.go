@@ -0,0 +1,417 @@
+package screenshot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FitMode 缩放到目标尺寸的方式
+type FitMode string
+
+const (
+	FitInside  FitMode = "inside"  // 等比缩放到目标尺寸以内，输出可能小于目标尺寸
+	FitContain FitMode = "contain" // 等比缩放到目标尺寸以内，四周用白色补齐到目标尺寸
+	FitCover   FitMode = "cover"   // 等比缩放到铺满目标尺寸，水平居中、从顶部开始裁掉多余部分
+	FitFill    FitMode = "fill"    // 拉伸到目标尺寸，不保持宽高比
+)
+
+// ParseFitMode 解析缩放方式，为空时返回 def
+func ParseFitMode(name string, def FitMode) (FitMode, error) {
+	switch mode := FitMode(strings.ToLower(name)); mode {
+	case "":
+		return def, nil
+	case FitInside, FitContain, FitCover, FitFill:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("不支持的缩放方式: %s（可用: inside, contain, cover, fill）", name)
+	}
+}
+
+// Resize 截图后缩放的目标尺寸，宽或高为0时按另一边等比缩放
+type Resize struct {
+	Width  int
+	Height int
+	Fit    FitMode
+}
+
+// ParseResize 解析 "宽x高"、"宽" 或 "x高" 形式的尺寸
+func ParseResize(value string, fit FitMode) (*Resize, error) {
+	w, h, _ := strings.Cut(strings.ToLower(value), "x")
+	r := &Resize{Fit: fit}
+	var err error
+	if w != "" {
+		if r.Width, err = strconv.Atoi(w); err != nil || r.Width <= 0 {
+			return nil, fmt.Errorf("无效的尺寸: %s", value)
+		}
+	}
+	if h != "" {
+		if r.Height, err = strconv.Atoi(h); err != nil || r.Height <= 0 {
+			return nil, fmt.Errorf("无效的尺寸: %s", value)
+		}
+	}
+	if r.Width == 0 && r.Height == 0 {
+		return nil, fmt.Errorf("无效的尺寸: %s", value)
+	}
+	return r, nil
+}
+
+// 缩放后图片的最大边长和总像素数，避免缩放参数导致占用过多内存
+const (
+	maxImageSide   = 16384
+	maxImagePixels = 64 << 20
+)
+
+// processing 是否需要截图后处理
+func (o Options) processing() bool {
+	return o.Crop != nil || o.Resize != nil || o.Thumbnail != nil || o.Watermark || o.OptimizePNG
+}
+
+// validateProcessing 检查输出格式是否支持截图后处理
+func (o Options) validateProcessing() error {
+	switch o.Format {
+	case FormatPDF:
+		return errors.New("PDF格式不支持截图后处理")
+	case FormatWebP:
+		return errors.New("WebP格式不支持截图后处理，请使用PNG或JPEG")
+	}
+	for _, r := range []*Resize{o.Resize, o.Thumbnail} {
+		if r != nil && (r.Width > maxImageSide || r.Height > maxImageSide) {
+			return fmt.Errorf("缩放尺寸不能超过 %d 像素", maxImageSide)
+		}
+		if r != nil && r.Width*r.Height > maxImagePixels {
+			return fmt.Errorf("缩放后的总像素数不能超过 %d", maxImagePixels)
+		}
+	}
+	return nil
+}
+
+// processResult 对截图结果依次执行裁剪、缩放、添加水印，再按输出格式编码。
+// 截图时使用无损的PNG，options 为最终的输出选项
+func processResult(result *ScreenshotResult, options Options) error {
+	if result.Image != nil {
+		img, err := decodeImage(result.Image)
+		if err != nil {
+			return err
+		}
+		if options.Crop != nil {
+			if img, err = crop(img, *options.Crop); err != nil {
+				return err
+			}
+		}
+
+		// 缩略图从裁剪后的原图生成，不带水印
+		if options.Thumbnail != nil {
+			thumb := resize(img, *options.Thumbnail)
+			if result.Thumbnail, err = encodeImage(thumb, options); err != nil {
+				return err
+			}
+		}
+
+		if result.Image, err = encodeImage(finishImage(img, result, options), options); err != nil {
+			return err
+		}
+	}
+
+	// 元素截图和步骤截图只缩放和添加水印
+	for i, shot := range result.Shots {
+		img, err := decodeImage(shot.Image)
+		if err != nil {
+			return err
+		}
+		if result.Shots[i].Image, err = encodeImage(finishImage(img, result, options), options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finishImage 缩放并添加水印
+func finishImage(img image.Image, result *ScreenshotResult, options Options) image.Image {
+	if options.Resize != nil {
+		img = resize(img, *options.Resize)
+	}
+	if options.Watermark {
+		img = watermark(img, result.URL, result.Timestamp)
+	}
+	return img
+}
+
+func decodeImage(data []byte) (image.Image, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("解码截图失败: %w", err)
+	}
+	return img, nil
+}
+
+// encodeImage 按输出格式编码，OptimizePNG 时使用最高压缩率，颜色不超过256种时使用调色板
+func encodeImage(img image.Image, options Options) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch options.Format {
+	case FormatJPEG:
+		quality := options.Quality
+		if quality <= 0 || quality > 100 {
+			quality = 90
+		}
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	default:
+		encoder := png.Encoder{CompressionLevel: png.DefaultCompression}
+		if options.OptimizePNG {
+			encoder.CompressionLevel = png.BestCompression
+			if p := toPaletted(img); p != nil {
+				img = p
+			}
+		}
+		err = encoder.Encode(&buf, img)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("编码图片失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// toPaletted 颜色不超过256种时无损转换为调色板图片，否则返回 nil
+func toPaletted(img image.Image) *image.Paletted {
+	b := img.Bounds()
+	index := make(map[color.NRGBA]uint8)
+	var palette color.Palette
+	out := image.NewPaletted(b, nil)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			i, ok := index[c]
+			if !ok {
+				if len(palette) == 256 {
+					return nil
+				}
+				i = uint8(len(palette))
+				index[c] = i
+				palette = append(palette, c)
+			}
+			out.SetColorIndex(x, y, i)
+		}
+	}
+	out.Palette = palette
+	return out
+}
+
+// crop 裁剪图片，区域超出图片的部分被忽略
+func crop(img image.Image, clip Clip) (image.Image, error) {
+	r := image.Rect(
+		int(math.Floor(clip.X)), int(math.Floor(clip.Y)),
+		int(math.Ceil(clip.X+clip.Width)), int(math.Ceil(clip.Y+clip.Height)),
+	).Add(img.Bounds().Min).Intersect(img.Bounds())
+	if r.Empty() {
+		return nil, fmt.Errorf("裁剪区域不在截图范围内（截图尺寸 %dx%d）", img.Bounds().Dx(), img.Bounds().Dy())
+	}
+	out := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(out, out.Rect, img, r.Min, draw.Src)
+	return out, nil
+}
+
+// limitSize 等比缩小尺寸，使边长不超过 maxImageSide、总像素数不超过 maxImagePixels
+func limitSize(w, h int) (int, int) {
+	scale := math.Min(1, float64(maxImageSide)/float64(max(w, h)))
+	scale = math.Min(scale, math.Sqrt(float64(maxImagePixels)/(float64(w)*float64(h))))
+	if scale >= 1 {
+		return w, h
+	}
+	return max(1, int(float64(w)*scale)), max(1, int(float64(h)*scale))
+}
+
+// resize 按缩放方式缩放到目标尺寸
+func resize(img image.Image, r Resize) image.Image {
+	b := img.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	w, h := r.Width, r.Height
+
+	// 只指定一边时等比缩放，算出的另一边超过上限时两边同比缩小
+	if w == 0 || h == 0 {
+		if w == 0 {
+			w = max(1, int(math.Round(float64(sw)*float64(h)/float64(sh))))
+		} else {
+			h = max(1, int(math.Round(float64(sh)*float64(w)/float64(sw))))
+		}
+		w, h = limitSize(w, h)
+		return resample(img, w, h)
+	}
+
+	scaleX, scaleY := float64(w)/float64(sw), float64(h)/float64(sh)
+	switch r.Fit {
+	case FitFill:
+		return resample(img, w, h)
+
+	case FitCover:
+		// 先裁剪原图再缩放，水平居中，保留页面顶部
+		scale := math.Max(scaleX, scaleY)
+		cw := min(sw, int(math.Round(float64(w)/scale)))
+		ch := min(sh, int(math.Round(float64(h)/scale)))
+		x0 := b.Min.X + (sw-cw)/2
+		cropped := image.NewRGBA(image.Rect(0, 0, cw, ch))
+		draw.Draw(cropped, cropped.Rect, img, image.Pt(x0, b.Min.Y), draw.Src)
+		return resample(cropped, w, h)
+
+	case FitContain:
+		scale := math.Min(scaleX, scaleY)
+		fw := max(1, int(math.Round(float64(sw)*scale)))
+		fh := max(1, int(math.Round(float64(sh)*scale)))
+		fitted := resample(img, fw, fh)
+		out := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.Draw(out, out.Rect, image.White, image.Point{}, draw.Src)
+		offset := image.Pt((w-fw)/2, (h-fh)/2)
+		draw.Draw(out, fitted.Rect.Add(offset), fitted, image.Point{}, draw.Src)
+		return out
+
+	default: // FitInside
+		scale := math.Min(scaleX, scaleY)
+		fw := max(1, int(math.Round(float64(sw)*scale)))
+		fh := max(1, int(math.Round(float64(sh)*scale)))
+		return resample(img, fw, fh)
+	}
+}
+
+// sampleWeight 输出像素对应的一个源像素及其权重
+type sampleWeight struct {
+	index  int
+	weight float32
+}
+
+// sampleWeights 计算一维缩放时每个输出像素的源像素权重：
+// 缩小时按覆盖面积取平均，放大时双线性插值
+func sampleWeights(src, dst int) [][]sampleWeight {
+	scale := float64(src) / float64(dst)
+	weights := make([][]sampleWeight, dst)
+	for i := range weights {
+		if scale >= 1 {
+			start, end := float64(i)*scale, float64(i+1)*scale
+			var sum float64
+			for j := int(start); j < src && float64(j) < end; j++ {
+				w := math.Min(end, float64(j+1)) - math.Max(start, float64(j))
+				if w > 0 {
+					weights[i] = append(weights[i], sampleWeight{j, float32(w)})
+					sum += w
+				}
+			}
+			for k := range weights[i] {
+				weights[i][k].weight /= float32(sum)
+			}
+			continue
+		}
+
+		center := (float64(i)+0.5)*scale - 0.5
+		j := math.Floor(center)
+		frac := float32(center - j)
+		j0 := min(max(int(j), 0), src-1)
+		j1 := min(max(int(j)+1, 0), src-1)
+		weights[i] = []sampleWeight{{j0, 1 - frac}, {j1, frac}}
+	}
+	return weights
+}
+
+// resample 缩放到指定尺寸，先水平后垂直两次一维缩放，在预乘透明度的RGBA空间中计算
+func resample(img image.Image, w, h int) *image.RGBA {
+	src, ok := img.(*image.RGBA)
+	if !ok || src.Rect.Min != (image.Point{}) {
+		b := img.Bounds()
+		src = image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(src, src.Rect, img, b.Min, draw.Src)
+	}
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	if sw == w && sh == h {
+		return src
+	}
+
+	// 水平方向
+	tmp := image.NewRGBA(image.Rect(0, 0, w, sh))
+	xWeights := sampleWeights(sw, w)
+	for y := 0; y < sh; y++ {
+		row := src.Pix[y*src.Stride:]
+		out := tmp.Pix[y*tmp.Stride:]
+		for x, weights := range xWeights {
+			var sum [4]float32
+			for _, sw := range weights {
+				p := row[sw.index*4:]
+				for c := 0; c < 4; c++ {
+					sum[c] += float32(p[c]) * sw.weight
+				}
+			}
+			for c := 0; c < 4; c++ {
+				out[x*4+c] = clampByte(sum[c])
+			}
+		}
+	}
+
+	// 垂直方向
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y, weights := range sampleWeights(sh, h) {
+		out := dst.Pix[y*dst.Stride:]
+		for x := 0; x < w; x++ {
+			var sum [4]float32
+			for _, sw := range weights {
+				p := tmp.Pix[sw.index*tmp.Stride+x*4:]
+				for c := 0; c < 4; c++ {
+					sum[c] += float32(p[c]) * sw.weight
+				}
+			}
+			for c := 0; c < 4; c++ {
+				out[x*4+c] = clampByte(sum[c])
+			}
+		}
+	}
+	return dst
+}
+
+func clampByte(v float32) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
+
+// watermark 在图片底部添加半透明的横条，显示URL和截图时间，文字过长时截断
+func watermark(img image.Image, url string, ts time.Time) image.Image {
+	b := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(out, out.Rect, img, b.Min, draw.Src)
+
+	// 按图片宽度放大文字，宽1280像素的截图放大2倍
+	scale := max(1, b.Dx()/640)
+	padding := 4 * scale
+	barHeight := glyphHeight*scale + 2*padding
+	if barHeight > b.Dy() {
+		return out
+	}
+
+	bar := image.Rect(0, b.Dy()-barHeight, b.Dx(), b.Dy())
+	draw.Draw(out, bar, image.NewUniform(color.NRGBA{A: 160}), image.Point{}, draw.Over)
+
+	stamp := ts.Format("2006-01-02 15:04:05 MST")
+	text := url + "  " + stamp
+	available := b.Dx() - 2*padding
+	if textWidth(text, scale) > available {
+		// 优先保留时间，截断URL
+		for len(url) > 0 && textWidth(url+"...  "+stamp, scale) > available {
+			url = url[:len(url)-1]
+		}
+		text = url + "...  " + stamp
+		if textWidth(text, scale) > available {
+			text = stamp
+		}
+	}
+	drawText(out, padding, bar.Min.Y+padding, text, scale, color.White)
+	return out
+}
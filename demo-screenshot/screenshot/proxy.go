@@ -0,0 +1,156 @@
+package screenshot
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+	"time"
+)
+
+// policyProxy 按URL策略拨号的本地HTTP代理。浏览器的所有连接（包括WebSocket）都经过该代理，
+// 由代理解析主机名并只连接检查过的地址，浏览器自己不再解析主机名
+type policyProxy struct {
+	policy   *URLPolicy
+	listener net.Listener
+	server   *http.Server
+	forward  *httputil.ReverseProxy
+
+	mu      sync.Mutex
+	tunnels map[net.Conn]struct{} // 隧道的两端连接，劫持后不再由 server 管理
+	closed  bool
+}
+
+// startPolicyProxy 在回环地址上启动代理
+func startPolicyProxy(policy *URLPolicy) (*policyProxy, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	p := &policyProxy{policy: policy, listener: ln, tunnels: make(map[net.Conn]struct{})}
+	p.forward = &httputil.ReverseProxy{
+		// 代理请求的URL已经是完整的地址，不需要改写
+		Rewrite: func(*httputil.ProxyRequest) {},
+		Transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         policy.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			IdleConnTimeout:     30 * time.Second,
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+		},
+	}
+	p.server = &http.Server{Handler: p, ReadHeaderTimeout: 30 * time.Second}
+	go p.server.Serve(ln)
+	return p, nil
+}
+
+// url 返回传给浏览器 --proxy-server 的地址
+func (p *policyProxy) url() string {
+	return "http://" + p.listener.Addr().String()
+}
+
+// Close 关闭代理和所有隧道连接
+func (p *policyProxy) Close() error {
+	err := p.server.Close()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for conn := range p.tunnels {
+		conn.Close()
+	}
+	p.tunnels = nil
+	return err
+}
+
+// track 记录隧道连接，代理已关闭时返回false
+func (p *policyProxy) track(conns ...net.Conn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false
+	}
+	for _, conn := range conns {
+		p.tunnels[conn] = struct{}{}
+	}
+	return true
+}
+
+func (p *policyProxy) untrack(conns ...net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, conn := range conns {
+		delete(p.tunnels, conn)
+	}
+}
+
+func (p *policyProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+	if r.URL.Host == "" {
+		http.Error(w, "只支持代理请求", http.StatusBadRequest)
+		return
+	}
+	p.forward.ServeHTTP(w, r)
+}
+
+// tunnel 处理HTTPS和WebSocket使用的CONNECT隧道
+func (p *policyProxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	dst, err := p.policy.DialContext(r.Context(), "tcp", r.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		dst.Close()
+		http.Error(w, "不支持隧道连接", http.StatusInternalServerError)
+		return
+	}
+	src, buf, err := hijacker.Hijack()
+	if err != nil {
+		dst.Close()
+		return
+	}
+	if !p.track(src, dst) {
+		src.Close()
+		dst.Close()
+		return
+	}
+
+	// 任一方向结束后关闭两端
+	var once sync.Once
+	closeBoth := func() {
+		once.Do(func() {
+			p.untrack(src, dst)
+			src.Close()
+			dst.Close()
+		})
+	}
+	if _, err := src.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		closeBoth()
+		return
+	}
+
+	go func() {
+		defer closeBoth()
+		// 先发送浏览器已经写入缓冲区的数据
+		if n := buf.Reader.Buffered(); n > 0 {
+			data, _ := buf.Reader.Peek(n)
+			if _, err := dst.Write(data); err != nil {
+				return
+			}
+		}
+		io.Copy(dst, src)
+	}()
+	go func() {
+		defer closeBoth()
+		io.Copy(src, dst)
+	}()
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/chromedp/cdproto/emulation"
 	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/cdproto/page"
 	"github.com/chromedp/chromedp"
@@ -14,35 +13,97 @@ import (
 
 // Options 包含截图的配置选项
 type Options struct {
-	Width       int
-	Height      int
-	MobileMode  bool
-	WaitTime    time.Duration
-	FullPage    bool
-	UserAgent   string
-	Timeout     time.Duration
-	BlockImages bool   // 是否屏蔽图片加载
-	BlockJS     bool   // 是否屏蔽JavaScript
-	Selector    string // 等待指定元素出现
+	Width              int
+	Height             int
+	MobileMode         bool
+	WaitTime           time.Duration
+	FullPage           bool
+	MaxHeight          int            // 全页面截图的最大高度（CSS像素），0表示 DefaultMaxHeight
+	Scroll             *ScrollOptions // 截图前分步滚动页面触发懒加载，为空表示不滚动
+	UserAgent          string
+	Timeout            time.Duration
+	BlockImages        bool                   // 是否屏蔽图片加载
+	BlockJS            bool                   // 是否屏蔽JavaScript
+	BlockResources     []network.ResourceType // 按资源类型屏蔽请求
+	Blocklist          *Blocklist             // 屏蔽广告和跟踪器的域名列表
+	Selector           string                 // 等待指定元素出现
+	WaitUntil          []WaitEvent            // 截图前等待的页面事件，设置后不再使用固定等待时间
+	WaitExpression     string                 // 等待JavaScript表达式的值为真
+	NetworkIdle        time.Duration          // 没有网络请求持续多久视为网络空闲
+	WaitDeadline       time.Duration          // 等待的总时长上限，超时后直接截图，0表示不限制
+	Format             Format                 // 输出格式，默认PNG
+	Quality            int                    // JPEG/WebP的压缩质量(1-100)
+	PDF                PDFOptions             // 输出格式为PDF时的页面设置
+	Element            string                 // 只截取该选择器匹配的第一个元素
+	AllElements        bool                   // 截取Element匹配的所有元素，结果保存在Shots中
+	MaxElements        int                    // AllElements 时最多截取的元素数，0表示 DefaultMaxElements
+	Padding            int                    // 元素截图四周的留白（像素）
+	Clip               *Clip                  // 只截取页面上的指定区域
+	Device             *Device                // 设备仿真预设，设置后忽略Width和Height
+	Landscape          bool                   // 横屏
+	DeviceScaleFactor  float64                // 设备像素比，0表示使用设备预设或1
+	Headers            map[string]string      // 附加到截图URL所在的源的请求的HTTP请求头
+	Cookies            []Cookie               // 导航前写入的Cookie
+	BasicAuth          *BasicAuth             // HTTP基本认证，只提供给截图URL所在的源
+	LocalStorage       map[string]string      // 导航前写入的localStorage，只对截图URL所在的源生效
+	SessionStorage     map[string]string      // 导航前写入的sessionStorage
+	Steps              []Step                 // 页面就绪后、截图前依次执行的交互步骤
+	Locate             []string               // 截图前记录这些选择器匹配的元素在截图中的位置，结果保存在 Regions 中
+	Diagnostics        bool                   // 收集HAR、控制台消息、JS异常和性能指标
+	URLPolicy          *URLPolicy             // 限制页面、重定向和子资源可以访问的URL，为空表示不限制
+	DisableWebSecurity bool                   // 关闭浏览器的同源策略，只对每次截图启动的浏览器生效
+	Crop               *Clip                  // 截图后裁剪的区域（图片像素），在缩放之前进行
+	Resize             *Resize                // 截图后缩放到的尺寸
+	Thumbnail          *Resize                // 另外生成的缩略图尺寸，结果保存在 Thumbnail 中
+	Watermark          bool                   // 在图片底部添加URL和截图时间的水印
+	OptimizePNG        bool                   // 使用最高压缩率，颜色较少时转为调色板PNG
+	ColorScheme        ColorScheme            // 模拟 prefers-color-scheme
+	ReducedMotion      bool                   // 模拟 prefers-reduced-motion: reduce
+	Media              MediaType              // 模拟CSS媒体类型，如 print
+	Locale             string                 // 语言，同时设置 Accept-Language 和 navigator.language
+	Timezone           string                 // 时区，如 Asia/Shanghai
+	Geolocation        *Geolocation           // 模拟的地理位置，并授予截图URL所在的源读取权限
+	DisableAnimations  bool                   // 让CSS动画和过渡立即结束，截图前结束剩余的动画
+	CSS                string                 // 页面加载后注入的CSS
+	Script             string                 // 页面加载后执行的JavaScript，返回Promise时等待其完成
+	Hide               []string               // 隐藏这些选择器匹配的元素（visibility: hidden，保留占位）
+	Remove             []string               // 删除这些选择器匹配的元素
+	HideConsent        bool                   // 隐藏常见的Cookie同意弹窗
 }
 
 // TimingInfo 包含截图过程的耗时信息
 type TimingInfo struct {
-	StartTime      time.Time
-	BrowserStart   time.Duration
-	Navigation     time.Duration
-	WaitComplete   time.Duration
-	ScreenshotTime time.Duration
-	TotalTime      time.Duration
+	StartTime        time.Time
+	BrowserStart     time.Duration // 启动浏览器或打开标签页，到可以执行命令为止
+	Navigation       time.Duration // 从开始导航到主框架提交导航（收到响应并开始解析文档）
+	DOMContentLoaded time.Duration // 从开始导航到DOMContentLoaded事件
+	Load             time.Duration // 从开始导航到load事件
+	WaitComplete     time.Duration // 从开始导航到等待结束、页面就绪
+	ScreenshotTime   time.Duration // 截图及截图后处理
+	TotalTime        time.Duration
+	// DNS 和 Connect 由浏览器测量。浏览器经过 URLPolicy 的本地代理访问网页时，
+	// 两者是到 127.0.0.1 上代理的耗时，不反映目标网站；TLS 仍是经过代理隧道的端到端握手
+	DNS              time.Duration // 主文档请求的DNS解析，复用连接时为0
+	Connect          time.Duration // 主文档请求建立连接，包括TLS握手
+	TLS              time.Duration // 主文档请求的TLS握手
+	TTFB             time.Duration // 主文档请求发出后到收到响应头
+	WaitTimedOut     bool          // 等待超过WaitDeadline，截图时页面可能尚未完全就绪
+	BlockedResources int           // 按资源类型屏蔽的请求数
+	BlockedByList    int           // 按屏蔽列表屏蔽的请求数
+	BlockedByPolicy  int           // 被URL策略拒绝的请求数
 }
 
 // ScreenshotResult 包含截图结果和耗时信息
 type ScreenshotResult struct {
-	Image     []byte
-	Timing    TimingInfo
-	Error     error
-	URL       string
-	Timestamp time.Time
+	Image       []byte
+	Shots       []Shot       // 元素截图等产生的多张图片
+	Diagnostics *Diagnostics // 页面诊断信息，设置 Options.Diagnostics 时收集
+	Regions     []Clip       // Options.Locate 匹配的元素在截图中的位置（图片像素）
+	Thumbnail   []byte       // Options.Thumbnail 生成的缩略图，格式与 Image 相同
+	Timing      TimingInfo
+	Error       error
+	URL         string
+	Timestamp   time.Time
 }
 
 // DefaultOptions 返回默认的选项设置
@@ -52,16 +113,29 @@ func DefaultOptions() Options {
 		Height:      800,
 		MobileMode:  false,
 		WaitTime:    2 * time.Second,
+		NetworkIdle: 500 * time.Millisecond,
 		FullPage:    false,
 		Timeout:     30 * time.Second,
 		BlockImages: false,
 		BlockJS:     false,
+		Format:      FormatPNG,
+		Quality:     90,
+		PDF:         DefaultPDFOptions(),
 	}
 }
 
-// CaptureScreenshot 获取指定URL的网页截图
+// CaptureScreenshot 获取指定URL的网页截图，每次调用都会启动新的浏览器
 func CaptureScreenshot(url string, options Options) ([]byte, TimingInfo, error) {
-	result := ScreenshotResult{
+	result, err := Capture(url, options)
+	if err != nil {
+		return nil, result.Timing, err
+	}
+	return result.Image, result.Timing, nil
+}
+
+// Capture 获取指定URL的网页截图，返回包括多张图片在内的完整结果
+func Capture(url string, options Options) (*ScreenshotResult, error) {
+	result := &ScreenshotResult{
 		URL:       url,
 		Timestamp: time.Now(),
 		Timing: TimingInfo{
@@ -70,13 +144,47 @@ func CaptureScreenshot(url string, options Options) ([]byte, TimingInfo, error)
 	}
 
 	if url == "" {
-		return nil, result.Timing, errors.New("URL不能为空")
+		result.Error = errors.New("URL不能为空")
+		return result, result.Error
 	}
 
 	// 设置超时上下文
 	ctx, cancel := context.WithTimeout(context.Background(), options.Timeout)
 	defer cancel()
 
+	// 有URL策略时浏览器的连接经过按策略拨号的代理
+	proxyURL := ""
+	if options.URLPolicy != nil {
+		proxy, err := startPolicyProxy(options.URLPolicy)
+		if err != nil {
+			result.Error = fmt.Errorf("启动代理失败: %w", err)
+			return result, result.Error
+		}
+		defer proxy.Close()
+		proxyURL = proxy.url()
+	}
+
+	// 创建Chrome实例并记录时间
+	startBrowser := time.Now()
+	allocCtx, cancel := chromedp.NewExecAllocator(ctx, allocatorOptions(options, proxyURL)...)
+	defer cancel()
+
+	// 创建新的Chrome实例，浏览器在第一次执行时才真正启动
+	taskCtx, cancel := chromedp.NewContext(allocCtx)
+	defer cancel()
+
+	if err := chromedp.Run(taskCtx); err != nil {
+		result.Error = fmt.Errorf("启动浏览器失败: %w", err)
+		return result, result.Error
+	}
+	result.Timing.BrowserStart = time.Since(startBrowser)
+
+	err := capture(taskCtx, url, options, result)
+	return result, err
+}
+
+// allocatorOptions 返回启动浏览器的参数，proxyURL 不为空时所有连接经过该代理
+func allocatorOptions(options Options, proxyURL string) []chromedp.ExecAllocatorOption {
 	// 配置浏览器选项，优化启动参数
 	opts := append(chromedp.DefaultExecAllocatorOptions[:],
 		chromedp.Flag("headless", true),
@@ -84,7 +192,6 @@ func CaptureScreenshot(url string, options Options) ([]byte, TimingInfo, error)
 		chromedp.Flag("no-sandbox", true),
 		chromedp.Flag("disable-dev-shm-usage", true),
 		chromedp.Flag("disable-extensions", true),
-		chromedp.Flag("disable-web-security", true),
 		chromedp.WindowSize(options.Width, options.Height),
 		// 减少内存使用
 		chromedp.Flag("disable-software-rasterizer", true),
@@ -98,18 +205,34 @@ func CaptureScreenshot(url string, options Options) ([]byte, TimingInfo, error)
 		opts = append(opts, chromedp.UserAgent(options.UserAgent))
 	}
 
-	if options.MobileMode {
-		opts = append(opts, chromedp.Flag("enable-mobile", true))
+	// 回环地址默认不经过代理，<-loopback> 取消这一例外；WebRTC的UDP连接不经过代理，需要禁止
+	if proxyURL != "" {
+		opts = append(opts,
+			chromedp.ProxyServer(proxyURL),
+			chromedp.Flag("proxy-bypass-list", "<-loopback>"),
+			chromedp.Flag("force-webrtc-ip-handling-policy", "disable_non_proxied_udp"),
+		)
 	}
 
-	// 创建Chrome实例并记录时间
-	startBrowser := time.Now()
-	allocCtx, cancel := chromedp.NewExecAllocator(ctx, opts...)
-	defer cancel()
+	// 关闭同源策略后页面脚本可以读取任意跨域响应，只在明确需要时开启
+	if options.DisableWebSecurity {
+		opts = append(opts, chromedp.Flag("disable-web-security", true))
+	}
 
-	// 创建新的Chrome实例
-	taskCtx, cancel := chromedp.NewContext(allocCtx)
-	defer cancel()
+	return opts
+}
+
+// capture 在已创建的标签页中完成导航、等待和截图，结果写入result
+func capture(taskCtx context.Context, url string, options Options, result *ScreenshotResult) error {
+	// 需要截图后处理时先截取无损的PNG，处理完成后再按输出格式编码
+	output := options
+	if options.processing() {
+		if err := options.validateProcessing(); err != nil {
+			result.Error = err
+			return err
+		}
+		options.Format = FormatPNG
+	}
 
 	// 设置错误处理器
 	chromedp.ListenTarget(taskCtx, func(ev interface{}) {
@@ -124,91 +247,120 @@ func CaptureScreenshot(url string, options Options) ([]byte, TimingInfo, error)
 		}
 	})
 
-	result.Timing.BrowserStart = time.Since(startBrowser)
-
 	// 执行截图
 	var buf []byte
 	var tasks []chromedp.Action
 
-	// 根据选项添加网络拦截任务
-	if options.BlockImages || options.BlockJS {
-		tasks = append(tasks, chromedp.ActionFunc(func(ctx context.Context) error {
-			// 设置网络拦截
-			if err := network.Enable().Do(ctx); err != nil {
-				return err
-			}
-
-			// 设置请求过滤器来阻止特定资源类型
-			blockedURLs := []string{}
-			
-			if options.BlockImages {
-				blockedURLs = append(blockedURLs, 
-					"*.jpg", "*.jpeg", "*.png", "*.gif", "*.webp", "*.svg", "*.ico")
-			}
-			
-			if options.BlockJS {
-				blockedURLs = append(blockedURLs, 
-					"*.js", "*.mjs", "*.jsx")
-			}
-
-			// 注册请求拦截
-			if len(blockedURLs) > 0 {
-				return network.SetBlockedURLs(blockedURLs).Do(ctx)
-			}
-
-			return nil
-		}))
-	}
-
-	// 设置设备仿真（如果需要）
-	if options.MobileMode {
-		tasks = append(tasks, emulation.SetDeviceMetricsOverride(
-			int64(options.Width),
-			int64(options.Height),
-			1.0,
-			true,
-		))
-	}
-
-	// 开始页面导航
-	startNav := time.Now()
-	tasks = append(tasks, chromedp.Navigate(url))
+	// 导航前检查URL策略，页面中的重定向和子资源由拦截器检查
+	if options.URLPolicy != nil {
+		if err := options.URLPolicy.Check(taskCtx, url); err != nil {
+			result.Error = err
+			return err
+		}
+	}
+
+	// 根据选项拦截请求，按URL策略、资源类型和屏蔽列表屏蔽，响应HTTP认证质询
+	in := newInterceptor(url, options)
+	if in != nil {
+		defer in.report(&result.Timing)
+		tasks = append(tasks, in.enableAction(taskCtx))
+	}
+	if options.URLPolicy != nil {
+		if schemes := options.URLPolicy.blockedSchemes(); len(schemes) > 0 {
+			tasks = append(tasks, blockSchemesAction(schemes))
+		}
+	}
+
+	// 设置视口尺寸和设备仿真，浏览器池中的标签页共用同一个窗口，需要单独设置
+	tasks = append(tasks, emulateAction(options))
+
+	// 设置配色方案、语言、时区等渲染环境
+	if options.usesEnvironment() {
+		tasks = append(tasks, environmentAction(url, options))
+	}
+
+	// 设置请求头、Cookie和本地存储，访问需要登录的页面
+	if options.usesAuth() {
+		tasks = append(tasks, authAction(url, options))
+	}
+
+	// 在导航前开始监听页面事件
+	waiter := newPageWaiter(taskCtx)
+	timer := newTimingRecorder(taskCtx)
+	defer timer.report(&result.Timing)
+
+	var diag *diagnosticsCollector
+	if options.Diagnostics {
+		diag = newDiagnosticsCollector(taskCtx)
+		defer func() { result.Diagnostics = diag.report() }()
+	}
+
+	// 开始页面导航，各阶段的时间点由页面事件记录
+	tasks = append(tasks, timer.startAction())
+	tasks = append(tasks, navigateAction(url, options))
 
 	// 等待页面加载
-	startWait := time.Now()
+	tasks = append(tasks, waitAction(waiter, options, result))
+	tasks = append(tasks, timer.mark(&timer.ready))
 
-	// 如果指定了选择器，等待该元素出现
-	if options.Selector != "" {
-		tasks = append(tasks, chromedp.WaitVisible(options.Selector))
-	} else {
-		// 否则使用固定等待时间
-		tasks = append(tasks, chromedp.Sleep(options.WaitTime))
+	// 注入CSS和脚本，隐藏Cookie弹窗、聊天窗口等元素
+	if options.usesInjection() {
+		tasks = append(tasks, injectAction(options))
 	}
 
-	// 等待页面加载完成
-	tasks = append(tasks, chromedp.ActionFunc(func(ctx context.Context) error {
-		result.Timing.Navigation = time.Since(startNav)
-		result.Timing.WaitComplete = time.Since(startWait)
-		return nil
-	}))
+	// 执行交互步骤，如关闭弹窗、填写表单
+	if len(options.Steps) > 0 {
+		tasks = append(tasks, stepsAction(options, result))
+	}
+
+	// 滚动页面触发懒加载，在交互步骤之后，页面内容已经确定
+	if options.Scroll != nil {
+		tasks = append(tasks, scrollAction(waiter, options))
+	}
+
+	// 记录需要定位的元素，如视觉比较时忽略的区域
+	if len(options.Locate) > 0 {
+		tasks = append(tasks, locateAction(result, options))
+	}
+
+	// 读取性能指标和导航计时
+	if diag != nil {
+		tasks = append(tasks, diag.collectAction())
+	}
+
+	if options.DisableAnimations {
+		tasks = append(tasks, finishAnimationsAction())
+	}
 
 	// 截图
-	startScreenshot := time.Now()
-	if options.FullPage {
-		tasks = append(tasks, chromedp.FullScreenshot(&buf, 100))
+	tasks = append(tasks, timer.mark(&timer.captureStart))
+	if options.Element != "" || options.Clip != nil {
+		tasks = append(tasks, regionCaptureAction(result, options))
 	} else {
-		tasks = append(tasks, chromedp.CaptureScreenshot(&buf))
+		tasks = append(tasks, captureAction(&buf, options))
 	}
 
 	if err := chromedp.Run(taskCtx, tasks...); err != nil {
+		// 重定向到被禁止的地址时，返回策略拒绝的原因而不是浏览器的网络错误
+		if in != nil && in.navigationError() != nil {
+			err = in.navigationError()
+		}
 		result.Error = err
-		return nil, result.Timing, err
+		return err
 	}
 
-	// 更新耗时信息
-	result.Timing.ScreenshotTime = time.Since(startScreenshot)
+	if buf != nil {
+		result.Image = buf
+	}
+	if output.processing() {
+		if err := processResult(result, output); err != nil {
+			result.Error = err
+			return err
+		}
+	}
+
+	// 截图耗时由 timer.report 在返回时写入
 	result.Timing.TotalTime = time.Since(result.Timing.StartTime)
-	result.Image = buf
 
-	return buf, result.Timing, nil
-} 
\ No newline at end of file
+	return nil
+}
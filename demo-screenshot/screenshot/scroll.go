@@ -0,0 +1,127 @@
+package screenshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// DefaultMaxHeight 全页面截图的默认最大高度（CSS像素），超过后Chrome可能截出空白
+const DefaultMaxHeight = 16384
+
+// ScrollOptions 截图前分步滚动页面，触发懒加载的图片和无限滚动的内容
+type ScrollOptions struct {
+	Step         int           // 每次滚动的距离（CSS像素），0表示一个视口的高度
+	Delay        time.Duration // 每次滚动后的等待时间
+	MaxSteps     int           // 最多滚动的次数，避免无限滚动的页面一直加载
+	ImageTimeout time.Duration // 滚动结束后等待图片加载和解码、网络空闲的时长上限
+	HideFixed    bool          // fixed 和 sticky 元素只在第一屏显示，不在长截图中重复出现或遮挡内容
+}
+
+// DefaultScrollOptions 返回默认的滚动选项
+func DefaultScrollOptions() ScrollOptions {
+	return ScrollOptions{
+		Delay:        200 * time.Millisecond,
+		MaxSteps:     50,
+		ImageTimeout: 5 * time.Second,
+	}
+}
+
+// scrollJS 把懒加载图片改为立即加载，分步滚动到页面底部或高度上限后回到顶部，
+// 页面在滚动过程中变长时继续滚动
+const scrollJS = `(async (step, delay, maxSteps, maxHeight) => {
+	const sleep = ms => new Promise(resolve => setTimeout(resolve, ms));
+	const height = () => Math.max(document.documentElement.scrollHeight, document.body ? document.body.scrollHeight : 0);
+	document.querySelectorAll('img[loading="lazy"], iframe[loading="lazy"]').forEach(el => el.loading = 'eager');
+	step = step || window.innerHeight;
+	let y = 0, steps = 0;
+	while (steps < maxSteps && y + window.innerHeight < Math.min(height(), maxHeight)) {
+		y += step;
+		window.scrollTo(0, y);
+		steps++;
+		await sleep(delay);
+	}
+	window.scrollTo(0, 0);
+})(%d, %d, %d, %d)`
+
+// decodeImagesJS 等待页面上所有图片加载并解码完成，加载失败的图片忽略
+const decodeImagesJS = `Promise.all(Array.from(document.images).map(img => img.decode().catch(() => {}))).then(() => true)`
+
+// hideFixedJS 在页面顶部时把 fixed 元素改为 absolute、sticky 元素改为 relative，
+// 它们停留在第一屏的位置，不会随长截图重复出现
+const hideFixedJS = `(() => {
+	for (const el of document.querySelectorAll('body *')) {
+		const position = getComputedStyle(el).position;
+		if (position === 'fixed') {
+			el.style.setProperty('position', 'absolute', 'important');
+		} else if (position === 'sticky') {
+			el.style.setProperty('position', 'relative', 'important');
+		}
+	}
+})()`
+
+// scrollAction 滚动页面触发懒加载，等待图片和网络请求完成，超时后继续截图
+func scrollAction(w *pageWaiter, options Options) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		scroll := *options.Scroll
+		if scroll.MaxSteps <= 0 {
+			scroll.MaxSteps = DefaultScrollOptions().MaxSteps
+		}
+
+		js := fmt.Sprintf(scrollJS, scroll.Step, scroll.Delay.Milliseconds(), scroll.MaxSteps, options.maxHeight())
+		if err := chromedp.Evaluate(js, nil, awaitPromise).Do(ctx); err != nil {
+			return fmt.Errorf("滚动页面失败: %w", err)
+		}
+
+		if scroll.ImageTimeout > 0 {
+			waitCtx, cancel := context.WithTimeout(ctx, scroll.ImageTimeout)
+			defer cancel()
+			var ok bool
+			err := chromedp.Evaluate(decodeImagesJS, &ok, awaitPromise).Do(waitCtx)
+			if err == nil {
+				err = w.waitNetworkIdle(waitCtx, options.NetworkIdle)
+			}
+			// 只是等待超时，已加载的内容仍然可以截图
+			if err != nil && !(errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil) {
+				return fmt.Errorf("等待图片加载失败: %w", err)
+			}
+		}
+
+		if scroll.HideFixed {
+			if err := chromedp.Evaluate(hideFixedJS, nil).Do(ctx); err != nil {
+				return fmt.Errorf("隐藏固定元素失败: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func awaitPromise(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+	return p.WithAwaitPromise(true)
+}
+
+// maxHeight 返回全页面截图的最大高度
+func (o Options) maxHeight() int {
+	if o.MaxHeight > 0 {
+		return o.MaxHeight
+	}
+	return DefaultMaxHeight
+}
+
+// fullPageClip 返回整个页面的区域，高度不超过 Options.MaxHeight
+func fullPageClip(ctx context.Context, options Options) (Clip, error) {
+	_, _, _, _, _, size, err := page.GetLayoutMetrics().Do(ctx)
+	if err != nil {
+		return Clip{}, fmt.Errorf("读取页面尺寸失败: %w", err)
+	}
+	return Clip{
+		Width:  math.Ceil(size.Width),
+		Height: math.Min(math.Ceil(size.Height), float64(options.maxHeight())),
+	}, nil
+}
@@ -0,0 +1,250 @@
+package screenshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/kb"
+)
+
+// 交互步骤的类型
+const (
+	StepClick      = "click"      // 点击元素
+	StepType       = "type"       // 在元素中输入文本，没有选择器时输入到当前焦点
+	StepPress      = "press"      // 按键，如 Enter、Escape、Tab
+	StepScroll     = "scroll"     // 滚动到元素，没有选择器时按X、Y滚动页面
+	StepHover      = "hover"      // 鼠标悬停在元素上
+	StepWait       = "wait"       // 等待元素出现，没有选择器时等待Duration毫秒
+	StepEvaluate   = "evaluate"   // 执行JavaScript，支持返回Promise
+	StepScreenshot = "screenshot" // 截图，结果保存在Shots中，有选择器时只截取该元素
+)
+
+// Step 截图前在页面上执行的一个交互步骤
+type Step struct {
+	Action   string  `json:"action"`
+	Selector string  `json:"selector,omitempty"`
+	Text     string  `json:"text,omitempty"`     // type 输入的文本
+	Key      string  `json:"key,omitempty"`      // press 的按键名称
+	X        float64 `json:"x,omitempty"`        // scroll 水平滚动的像素
+	Y        float64 `json:"y,omitempty"`        // scroll 垂直滚动的像素
+	Duration int     `json:"duration,omitempty"` // wait 等待的毫秒数
+	Script   string  `json:"script,omitempty"`   // evaluate 执行的脚本
+	Name     string  `json:"name,omitempty"`     // screenshot 保存的文件名，默认为 step-序号.扩展名
+	Timeout  int     `json:"timeout,omitempty"`  // 等待元素的毫秒数，默认10秒
+	Optional bool    `json:"optional,omitempty"` // 失败时继续执行，如关闭不一定出现的弹窗
+}
+
+// 等待步骤中的元素出现的默认时长
+const defaultStepTimeout = 10 * time.Second
+
+// 按键名称
+var keyNames = map[string]string{
+	"enter":      kb.Enter,
+	"escape":     kb.Escape,
+	"esc":        kb.Escape,
+	"tab":        kb.Tab,
+	"backspace":  kb.Backspace,
+	"delete":     kb.Delete,
+	"arrowup":    kb.ArrowUp,
+	"arrowdown":  kb.ArrowDown,
+	"arrowleft":  kb.ArrowLeft,
+	"arrowright": kb.ArrowRight,
+	"pageup":     kb.PageUp,
+	"pagedown":   kb.PageDown,
+	"home":       kb.Home,
+	"end":        kb.End,
+	"space":      " ",
+}
+
+// ParseSteps 解析JSON格式的步骤列表并检查每个步骤的参数
+func ParseSteps(data []byte) ([]Step, error) {
+	var steps []Step
+	if err := json.Unmarshal(data, &steps); err != nil {
+		return nil, fmt.Errorf("解析步骤失败: %w", err)
+	}
+	if err := ValidateSteps(steps); err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
+
+// ValidateSteps 检查每个步骤的参数是否完整，screenshot 步骤的文件名不能重复
+func ValidateSteps(steps []Step) error {
+	names := make(map[string]bool)
+	for i, step := range steps {
+		if err := step.validate(); err != nil {
+			return fmt.Errorf("第%d步: %w", i+1, err)
+		}
+		if step.Name == "" {
+			continue
+		}
+		if names[step.Name] {
+			return fmt.Errorf("第%d步: 文件名重复: %s", i+1, step.Name)
+		}
+		names[step.Name] = true
+	}
+	return nil
+}
+
+func (s Step) validate() error {
+	switch s.Action {
+	case StepClick, StepHover:
+		if s.Selector == "" {
+			return fmt.Errorf("%s 需要指定 selector", s.Action)
+		}
+	case StepType:
+		if s.Text == "" {
+			return errors.New("type 需要指定 text")
+		}
+	case StepPress:
+		if _, ok := keyNames[strings.ToLower(s.Key)]; !ok && len([]rune(s.Key)) != 1 {
+			return fmt.Errorf("未知的按键: %s", s.Key)
+		}
+	case StepScroll:
+	case StepScreenshot:
+		// 文件名会作为zip中的条目名和命令行工具保存的文件名，不能包含路径
+		if strings.ContainsAny(s.Name, `/\`) || strings.Contains(s.Name, "..") {
+			return fmt.Errorf("无效的文件名: %s", s.Name)
+		}
+	case StepWait:
+		if s.Selector == "" && s.Duration <= 0 {
+			return errors.New("wait 需要指定 selector 或 duration")
+		}
+	case StepEvaluate:
+		if s.Script == "" {
+			return errors.New("evaluate 需要指定 script")
+		}
+	default:
+		return fmt.Errorf("未知的步骤: %s", s.Action)
+	}
+	return nil
+}
+
+// stepsAction 依次执行交互步骤，screenshot 步骤的截图追加到 result.Shots
+func stepsAction(options Options, result *ScreenshotResult) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		for i, step := range options.Steps {
+			err := runStep(ctx, i, step, options, result)
+			if err != nil && !step.Optional {
+				return fmt.Errorf("第%d步 %s 失败: %w", i+1, step.Action, err)
+			}
+		}
+		return nil
+	})
+}
+
+// runStep 执行一个步骤，等待元素的时长受步骤的超时限制
+func runStep(ctx context.Context, i int, step Step, options Options, result *ScreenshotResult) error {
+	timeout := defaultStepTimeout
+	if step.Timeout > 0 {
+		timeout = time.Duration(step.Timeout) * time.Millisecond
+	}
+	stepCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	switch step.Action {
+	case StepClick:
+		return chromedp.Click(step.Selector, chromedp.NodeVisible).Do(stepCtx)
+
+	case StepType:
+		if step.Selector == "" {
+			return chromedp.KeyEvent(step.Text).Do(stepCtx)
+		}
+		return chromedp.SendKeys(step.Selector, step.Text, chromedp.NodeVisible).Do(stepCtx)
+
+	case StepPress:
+		key, ok := keyNames[strings.ToLower(step.Key)]
+		if !ok {
+			key = step.Key
+		}
+		return chromedp.KeyEvent(key).Do(stepCtx)
+
+	case StepScroll:
+		if step.Selector != "" {
+			return chromedp.ScrollIntoView(step.Selector, chromedp.NodeVisible).Do(stepCtx)
+		}
+		return chromedp.Evaluate(fmt.Sprintf("window.scrollBy(%g, %g)", step.X, step.Y), nil).Do(stepCtx)
+
+	case StepHover:
+		return hover(stepCtx, step.Selector)
+
+	case StepWait:
+		if step.Selector != "" {
+			return chromedp.WaitVisible(step.Selector).Do(stepCtx)
+		}
+		// 固定等待不受元素超时限制
+		return chromedp.Sleep(time.Duration(step.Duration) * time.Millisecond).Do(ctx)
+
+	case StepEvaluate:
+		return chromedp.Evaluate(step.Script, nil, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+			return p.WithAwaitPromise(true)
+		}).Do(stepCtx)
+
+	case StepScreenshot:
+		return stepScreenshot(stepCtx, i, step, options, result)
+	}
+	return fmt.Errorf("未知的步骤: %s", step.Action)
+}
+
+// hover 把鼠标移动到元素中心
+func hover(ctx context.Context, selector string) error {
+	if err := chromedp.ScrollIntoView(selector, chromedp.NodeVisible).Do(ctx); err != nil {
+		return err
+	}
+	sel, err := json.Marshal(selector)
+	if err != nil {
+		return err
+	}
+
+	var center struct{ X, Y float64 }
+	js := fmt.Sprintf(`(() => {
+		const r = document.querySelector(%s).getBoundingClientRect();
+		return {X: r.left + r.width / 2, Y: r.top + r.height / 2};
+	})()`, sel)
+	if err := chromedp.Evaluate(js, &center).Do(ctx); err != nil {
+		return err
+	}
+	return input.DispatchMouseEvent(input.MouseMoved, center.X, center.Y).Do(ctx)
+}
+
+// stepScreenshot 截取当前页面或指定元素
+func stepScreenshot(ctx context.Context, i int, step Step, options Options, result *ScreenshotResult) error {
+	var buf []byte
+	if step.Selector != "" {
+		if options.Format == FormatPDF {
+			return errors.New("PDF格式不支持元素截图")
+		}
+		if err := chromedp.WaitVisible(step.Selector).Do(ctx); err != nil {
+			return err
+		}
+		rects, err := elementRects(ctx, step.Selector)
+		if err != nil {
+			return err
+		}
+		if len(rects) == 0 {
+			return fmt.Errorf("未找到可见的元素: %s", step.Selector)
+		}
+		if buf, err = captureClip(ctx, rects[0].pad(options.Padding), options); err != nil {
+			return err
+		}
+	} else if err := captureAction(&buf, options).Do(ctx); err != nil {
+		return err
+	}
+
+	name := step.Name
+	if name == "" {
+		name = fmt.Sprintf("step-%d", i+1)
+	}
+	if !strings.Contains(name, ".") {
+		name += "." + options.Format.Extension()
+	}
+	result.Shots = append(result.Shots, Shot{Name: name, Image: buf})
+	return nil
+}
@@ -0,0 +1,132 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// timingRecorder 根据DevTools事件记录导航各阶段的时间点，在导航前创建
+type timingRecorder struct {
+	mu        sync.Mutex
+	mainFrame cdp.FrameID
+	document  network.RequestID       // 主文档的请求，重定向沿用同一个ID
+	docTiming *network.ResourceTiming // 主文档最终响应的网络计时
+
+	start            time.Time // 开始导航
+	committed        time.Time // 主框架提交导航，开始解析新文档
+	domContentLoaded time.Time
+	loaded           time.Time
+	ready            time.Time // 等待结束
+	captureStart     time.Time // 开始截图
+}
+
+// newTimingRecorder 创建记录器并开始监听事件
+func newTimingRecorder(ctx context.Context) *timingRecorder {
+	t := &timingRecorder{}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.handle(ev, time.Now())
+	})
+	return t
+}
+
+func (t *timingRecorder) handle(ev interface{}, now time.Time) {
+	// 导航开始前的事件（如初始的 about:blank）不计入
+	if t.start.IsZero() {
+		return
+	}
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		// 导航请求的 RequestID 与 LoaderID 相同，子资源的请求不同
+		if ev.Type == network.ResourceTypeDocument && ev.FrameID == t.mainFrame &&
+			t.document == "" && string(ev.RequestID) == string(ev.LoaderID) {
+			t.document = ev.RequestID
+		}
+	case *network.EventResponseReceived:
+		if ev.RequestID == t.document && ev.Response != nil {
+			t.docTiming = ev.Response.Timing
+		}
+	case *page.EventFrameNavigated:
+		if ev.Frame.ParentID == "" && t.committed.IsZero() {
+			t.committed = now
+		}
+	case *page.EventDomContentEventFired:
+		if t.domContentLoaded.IsZero() {
+			t.domContentLoaded = now
+		}
+	case *page.EventLoadEventFired:
+		if t.loaded.IsZero() {
+			t.loaded = now
+		}
+	}
+}
+
+// startAction 开启网络事件并记录导航开始的时间，紧接在导航之前执行
+func (t *timingRecorder) startAction() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if err := network.Enable().Do(ctx); err != nil {
+			return fmt.Errorf("启用网络事件失败: %w", err)
+		}
+		tree, err := page.GetFrameTree().Do(ctx)
+		if err != nil {
+			return fmt.Errorf("读取页面框架失败: %w", err)
+		}
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.mainFrame = tree.Frame.ID
+		t.start = time.Now()
+		return nil
+	})
+}
+
+// mark 返回记录当前时间的动作
+func (t *timingRecorder) mark(at *time.Time) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		*at = time.Now()
+		return nil
+	})
+}
+
+// report 把各阶段耗时写入 timing，未发生的阶段为0
+func (t *timingRecorder) report(timing *TimingInfo) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	since := func(at time.Time) time.Duration {
+		if at.IsZero() || t.start.IsZero() {
+			return 0
+		}
+		return at.Sub(t.start)
+	}
+	timing.Navigation = since(t.committed)
+	timing.DOMContentLoaded = since(t.domContentLoaded)
+	timing.Load = since(t.loaded)
+	timing.WaitComplete = since(t.ready)
+	if !t.captureStart.IsZero() {
+		timing.ScreenshotTime = time.Since(t.captureStart)
+	}
+
+	// ResourceTiming 中的时间是相对于 RequestTime 的毫秒数，-1 表示没有该阶段（如复用连接）
+	if d := t.docTiming; d != nil {
+		span := func(start, end float64) time.Duration {
+			if start < 0 || end < start {
+				return 0
+			}
+			return time.Duration((end - start) * float64(time.Millisecond))
+		}
+		timing.DNS = span(d.DNSStart, d.DNSEnd)
+		timing.Connect = span(d.ConnectStart, d.ConnectEnd)
+		timing.TLS = span(d.SslStart, d.SslEnd)
+		timing.TTFB = span(d.SendEnd, d.ReceiveHeadersEnd)
+	}
+}
@@ -37,10 +37,20 @@ func FormatDuration(d time.Duration) string {
 // TimingToMap 将耗时信息转换为字典
 func TimingToMap(timing TimingInfo) map[string]interface{} {
 	return map[string]interface{}{
-		"browser_start": timing.BrowserStart.Seconds(),
-		"navigation":    timing.Navigation.Seconds(),
-		"wait_complete": timing.WaitComplete.Seconds(),
-		"screenshot":    timing.ScreenshotTime.Seconds(),
-		"total":         timing.TotalTime.Seconds(),
+		"browser_start":      timing.BrowserStart.Seconds(),
+		"navigation":         timing.Navigation.Seconds(),
+		"dom_content_loaded": timing.DOMContentLoaded.Seconds(),
+		"load":               timing.Load.Seconds(),
+		"wait_complete":      timing.WaitComplete.Seconds(),
+		"screenshot":         timing.ScreenshotTime.Seconds(),
+		"total":              timing.TotalTime.Seconds(),
+		"dns":                timing.DNS.Seconds(),
+		"connect":            timing.Connect.Seconds(),
+		"tls":                timing.TLS.Seconds(),
+		"ttfb":               timing.TTFB.Seconds(),
+		"wait_timed_out":     timing.WaitTimedOut,
+		"blocked_resources":  timing.BlockedResources,
+		"blocked_by_list":    timing.BlockedByList,
+		"blocked_by_policy":  timing.BlockedByPolicy,
 	}
-} 
\ No newline at end of file
+}
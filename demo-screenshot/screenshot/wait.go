@@ -0,0 +1,229 @@
+package screenshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// WaitEvent 截图前等待的页面事件
+type WaitEvent string
+
+const (
+	WaitDOMContentLoaded WaitEvent = "domcontentloaded" // DOM解析完成
+	WaitLoad             WaitEvent = "load"             // 页面及其资源加载完成
+	WaitNetworkIdle      WaitEvent = "networkidle"      // 一段时间内没有进行中的网络请求
+	WaitFonts            WaitEvent = "fonts"            // 网页字体加载完成
+)
+
+// ParseWaitEvents 解析逗号分隔的等待事件列表
+func ParseWaitEvents(value string) ([]WaitEvent, error) {
+	var events []WaitEvent
+	for _, name := range strings.Split(value, ",") {
+		event := WaitEvent(strings.ToLower(strings.TrimSpace(name)))
+		switch event {
+		case "":
+			continue
+		case WaitDOMContentLoaded, WaitLoad, WaitNetworkIdle, WaitFonts:
+			events = append(events, event)
+		default:
+			return nil, fmt.Errorf("未知的等待事件: %s", name)
+		}
+	}
+	return events, nil
+}
+
+// usesWaitEvents 是否使用事件驱动的等待，否则沿用固定等待时间或等待元素
+func (o Options) usesWaitEvents() bool {
+	return len(o.WaitUntil) > 0 || o.WaitExpression != ""
+}
+
+// pageWaiter 监听页面生命周期和网络事件，在导航前创建
+type pageWaiter struct {
+	domContentLoaded chan struct{}
+	loaded           chan struct{}
+
+	mu           sync.Mutex
+	inflight     map[network.RequestID]bool
+	lastActivity time.Time
+}
+
+// newPageWaiter 创建等待器并开始监听事件
+func newPageWaiter(ctx context.Context) *pageWaiter {
+	w := &pageWaiter{
+		domContentLoaded: make(chan struct{}),
+		loaded:           make(chan struct{}),
+		inflight:         make(map[network.RequestID]bool),
+		lastActivity:     time.Now(),
+	}
+	var dclOnce, loadOnce sync.Once
+
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *page.EventDomContentEventFired:
+			dclOnce.Do(func() { close(w.domContentLoaded) })
+		case *page.EventLoadEventFired:
+			loadOnce.Do(func() { close(w.loaded) })
+		case *network.EventRequestWillBeSent:
+			w.mu.Lock()
+			w.inflight[ev.RequestID] = true
+			w.lastActivity = time.Now()
+			w.mu.Unlock()
+		case *network.EventLoadingFinished:
+			w.finish(ev.RequestID)
+		case *network.EventLoadingFailed:
+			w.finish(ev.RequestID)
+		}
+	})
+	return w
+}
+
+func (w *pageWaiter) finish(id network.RequestID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.inflight, id)
+	w.lastActivity = time.Now()
+}
+
+// idleFor 返回网络已空闲的时长，有进行中的请求时返回0
+func (w *pageWaiter) idleFor() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.inflight) > 0 {
+		return 0
+	}
+	return time.Since(w.lastActivity)
+}
+
+// waitNetworkIdle 等待连续idle时长内没有进行中的网络请求
+func (w *pageWaiter) waitNetworkIdle(ctx context.Context, idle time.Duration) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if w.idleFor() >= idle {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// navigateAction 导航到URL，使用事件驱动等待时不等待load事件，由等待器决定何时截图
+func navigateAction(url string, options Options) chromedp.Action {
+	if !options.usesWaitEvents() {
+		return chromedp.Navigate(url)
+	}
+
+	var tasks chromedp.Tasks
+	if containsWaitEvent(options.WaitUntil, WaitNetworkIdle) {
+		tasks = append(tasks, network.Enable())
+	}
+	tasks = append(tasks, chromedp.ActionFunc(func(ctx context.Context) error {
+		_, _, errorText, err := page.Navigate(url).Do(ctx)
+		if err != nil {
+			return err
+		}
+		if errorText != "" {
+			return fmt.Errorf("页面加载失败: %s", errorText)
+		}
+		return nil
+	}))
+	return tasks
+}
+
+// waitAction 按选项等待页面就绪，超过WaitDeadline后不再等待，直接截图
+func waitAction(w *pageWaiter, options Options, result *ScreenshotResult) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		waitCtx := ctx
+		if options.WaitDeadline > 0 {
+			var cancel context.CancelFunc
+			waitCtx, cancel = context.WithTimeout(ctx, options.WaitDeadline)
+			defer cancel()
+		}
+
+		err := w.wait(waitCtx, options)
+		if err != nil && errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			// 只是等待超时，页面仍然可用
+			result.Timing.WaitTimedOut = true
+			return nil
+		}
+		return err
+	})
+}
+
+// wait 依次等待所有条件满足
+func (w *pageWaiter) wait(ctx context.Context, options Options) error {
+	if !options.usesWaitEvents() {
+		// 如果指定了选择器，等待该元素出现
+		if options.Selector != "" {
+			return chromedp.WaitVisible(options.Selector).Do(ctx)
+		}
+		// 否则使用固定等待时间
+		return chromedp.Sleep(options.WaitTime).Do(ctx)
+	}
+
+	for _, event := range options.WaitUntil {
+		var err error
+		switch event {
+		case WaitDOMContentLoaded:
+			err = waitChan(ctx, w.domContentLoaded)
+		case WaitLoad:
+			err = waitChan(ctx, w.loaded)
+		case WaitNetworkIdle:
+			err = w.waitNetworkIdle(ctx, options.NetworkIdle)
+		case WaitFonts:
+			var ready bool
+			err = chromedp.Evaluate(`document.fonts.ready.then(() => true)`, &ready,
+				func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+					return p.WithAwaitPromise(true)
+				}).Do(ctx)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if options.WaitExpression != "" {
+		err := chromedp.Poll(options.WaitExpression, nil,
+			chromedp.WithPollingInterval(100*time.Millisecond),
+			chromedp.WithPollingTimeout(0),
+		).Do(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	if options.Selector != "" {
+		return chromedp.WaitVisible(options.Selector).Do(ctx)
+	}
+	return nil
+}
+
+func waitChan(ctx context.Context, ch <-chan struct{}) error {
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func containsWaitEvent(events []WaitEvent, event WaitEvent) bool {
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
@@ -1,110 +1,947 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/fuwenhao/go-base/demo-screenshot/apikey"
+	"github.com/fuwenhao/go-base/demo-screenshot/cache"
+	"github.com/fuwenhao/go-base/demo-screenshot/diff"
+	"github.com/fuwenhao/go-base/demo-screenshot/jobs"
 	"github.com/fuwenhao/go-base/demo-screenshot/screenshot"
+	"github.com/fuwenhao/go-base/demo-screenshot/storage"
 )
 
 // 响应结构
 type Response struct {
-	Success bool                   `json:"success"`
-	Error   string                 `json:"error,omitempty"`
-	Timing  map[string]interface{} `json:"timing,omitempty"`
+	Success     bool                    `json:"success"`
+	Error       string                  `json:"error,omitempty"`
+	Timing      map[string]interface{}  `json:"timing,omitempty"`
+	Diagnostics *screenshot.Diagnostics `json:"diagnostics,omitempty"`
+	Thumbnail   string                  `json:"thumbnail,omitempty"` // 缩略图的data URI
 }
 
+// captureRequest POST请求的JSON请求体，用于传递不适合放在查询字符串中的认证信息和交互步骤，
+// 其他截图选项仍然通过查询参数指定
+type captureRequest struct {
+	URL            string                `json:"url"`
+	Headers        map[string]string     `json:"headers"`
+	Cookies        []screenshot.Cookie   `json:"cookies"`
+	BasicAuth      *screenshot.BasicAuth `json:"basic_auth"`
+	LocalStorage   map[string]string     `json:"local_storage"`
+	SessionStorage map[string]string     `json:"session_storage"`
+	Steps          []screenshot.Step     `json:"steps"`
+	CSS            string                `json:"css"` // 较长的CSS和脚本可以放在请求体中
+	Script         string                `json:"script"`
+	CallbackURL    string                `json:"callback_url"` // 只用于异步任务
+}
+
+// StoredResponse 请求带 store=1 时返回保存的对象信息，而不是图片
+type StoredResponse struct {
+	Success bool `json:"success"`
+	storage.Object
+	SourceURL string                 `json:"source_url"`
+	Cache     string                 `json:"cache,omitempty"`
+	Timing    map[string]interface{} `json:"timing,omitempty"`
+}
+
+// 常驻的浏览器池，所有请求共用
+var pool *screenshot.Pool
+
+// 广告和跟踪器屏蔽列表，请求带 block-ads=1 时使用
+var blocklist *screenshot.Blocklist
+
+// 截图结果缓存，为空表示不缓存
+var resultCache *cache.Cache
+
+// 异步截图任务队列
+var jobQueue *jobs.Queue
+
+// 截图的存储后端和命名模板，未配置时不能使用 store=1
+var objectStore storage.Storage
+var storeTemplate *storage.Template
+
+// 所有请求使用的URL策略，防止通过截图服务访问内网
+var urlPolicy = screenshot.DefaultURLPolicy()
+
+// 全页面截图的 max-height 参数上限
+var maxHeightLimit int
+
+// 元素API最多截取的元素数
+var maxElements int
+
+// 异步任务的截图超时时间上限，请求可以用 timeout 参数指定更短的时间
+var jobTimeout time.Duration
+
 func main() {
+	port := flag.Int("port", 8080, "服务端口")
+	poolOptions := screenshot.DefaultPoolOptions()
+	flag.IntVar(&poolOptions.Browsers, "browsers", poolOptions.Browsers, "常驻的浏览器进程数")
+	flag.IntVar(&poolOptions.MaxConcurrency, "concurrency", poolOptions.MaxConcurrency, "同时进行的截图数量上限")
+	flag.IntVar(&poolOptions.MaxCaptures, "recycle", poolOptions.MaxCaptures, "每个浏览器完成多少次截图后重启，0表示不限制")
+	blocklistFile := flag.String("blocklist", "", "EasyList或hosts格式的广告/跟踪器域名屏蔽列表")
+	schemes := flag.String("schemes", "http,https", "允许截图的URL协议，逗号分隔")
+	allowHosts := flag.String("allow-hosts", "", "只允许截取这些主机及其子域名，逗号分隔")
+	denyHosts := flag.String("deny-hosts", "", "禁止截取这些主机及其子域名，逗号分隔")
+	flag.BoolVar(&urlPolicy.AllowPrivate, "allow-private", false, "允许截取内网、回环和链路本地地址")
+	flag.BoolVar(&poolOptions.DisableWebSecurity, "disable-web-security", false, "关闭浏览器的同源策略（不安全）")
+	keysFile := flag.String("keys", "", "API密钥文件，指定后所有截图请求都需要API密钥")
+	adminToken := flag.String("admin-token", os.Getenv("SCREENSHOT_ADMIN_TOKEN"), "密钥管理接口的令牌，默认读取 SCREENSHOT_ADMIN_TOKEN 环境变量")
+	jobsDir := flag.String("jobs-dir", "data/jobs", "异步任务和结果的保存目录")
+	jobOptions := jobs.Options{Run: runJob, CheckCallback: urlPolicy.Check, DialCallback: urlPolicy.DialContext}
+	flag.IntVar(&jobOptions.Workers, "job-workers", 2, "同时执行的异步任务数")
+	flag.IntVar(&jobOptions.MaxPending, "max-pending", 100, "等待执行的异步任务数上限，0表示不限制")
+	flag.DurationVar(&jobOptions.Retention, "job-retention", 24*time.Hour, "已结束的异步任务和结果保留多久，0表示一直保留")
+	flag.IntVar(&maxElements, "max-elements", screenshot.DefaultMaxElements, "元素API最多截取的元素数，超过时返回400")
+	flag.IntVar(&maxHeightLimit, "max-height", screenshot.DefaultMaxHeight, "全页面截图的 max-height 参数上限（CSS像素）")
+	flag.DurationVar(&jobTimeout, "job-timeout", 2*time.Minute, "异步任务的截图超时时间上限")
+	cacheOptions := cache.Options{}
+	flag.DurationVar(&cacheOptions.TTL, "cache-ttl", 5*time.Minute, "截图结果的缓存时间，0表示不缓存")
+	cacheMemory := flag.Int64("cache-memory", 64, "内存缓存的上限（MB）")
+	cacheDisk := flag.Int64("cache-disk", 512, "磁盘缓存的上限（MB）")
+	flag.StringVar(&cacheOptions.Dir, "cache-dir", "data/cache", "磁盘缓存目录，为空时只使用内存缓存")
+	flag.StringVar(&jobOptions.CallbackSecret, "callback-secret", os.Getenv("SCREENSHOT_CALLBACK_SECRET"), "回调请求的签名密钥，默认读取 SCREENSHOT_CALLBACK_SECRET 环境变量")
+	storageConfig := storage.Config{}
+	flag.StringVar(&storageConfig.Type, "storage", "", "截图的存储后端: local 或 s3，为空时不保存")
+	flag.StringVar(&storageConfig.Dir, "storage-dir", "data/screenshots", "本地存储的目录")
+	flag.StringVar(&storageConfig.BaseURL, "storage-url", "", "对象的公开访问地址前缀，本地存储未指定时由本服务在 /files/ 下提供")
+	templateValue := flag.String("storage-template", storage.DefaultTemplate, "对象键的命名模板，可用变量: {id} {host} {path} {date} {time} {unix} {hash} {ext}")
+	storageRetention := flag.Duration("storage-retention", 0, "保存的截图保留多久，0表示一直保留")
+	flag.StringVar(&storageConfig.S3.Endpoint, "s3-endpoint", "", "S3兼容存储的地址，默认为AWS的区域地址")
+	flag.StringVar(&storageConfig.S3.Region, "s3-region", "us-east-1", "S3的区域")
+	flag.StringVar(&storageConfig.S3.Bucket, "s3-bucket", "", "S3存储桶")
+	flag.StringVar(&storageConfig.S3.AccessKey, "s3-access-key", os.Getenv("AWS_ACCESS_KEY_ID"), "S3的访问密钥ID，默认读取 AWS_ACCESS_KEY_ID 环境变量")
+	flag.StringVar(&storageConfig.S3.SecretKey, "s3-secret-key", os.Getenv("AWS_SECRET_ACCESS_KEY"), "S3的访问密钥，默认读取 AWS_SECRET_ACCESS_KEY 环境变量")
+	flag.BoolVar(&storageConfig.S3.PathStyle, "s3-path-style", false, "使用路径形式的存储桶地址（MinIO等需要）")
+	flag.Parse()
+	storageConfig.S3.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+
+	urlPolicy.AllowedSchemes = splitList(*schemes)
+	urlPolicy.AllowHosts = splitList(*allowHosts)
+	urlPolicy.DenyHosts = splitList(*denyHosts)
+	poolOptions.URLPolicy = urlPolicy
+	if urlPolicy.AllowPrivate {
+		log.Printf("警告: 已允许截取内网地址")
+	}
+
+	if *blocklistFile != "" {
+		var err error
+		blocklist, err = screenshot.LoadBlocklist(*blocklistFile)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		log.Printf("已加载屏蔽列表: %d 个域名", blocklist.Len())
+	}
+
+	// 创建截图结果缓存
+	var err error
+	if cacheOptions.TTL > 0 {
+		cacheOptions.MaxMemory = *cacheMemory << 20
+		cacheOptions.MaxDisk = *cacheDisk << 20
+		resultCache, err = cache.New(cacheOptions)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		log.Printf("已开启截图缓存: 有效期 %v，内存 %d MB，磁盘 %d MB", cacheOptions.TTL, *cacheMemory, *cacheDisk)
+	}
+
+	// 打开截图存储，本地存储未指定访问地址时由本服务提供文件
+	serveFiles := false
+	if storageConfig.Type != "" {
+		storeTemplate, err = storage.ParseTemplate(*templateValue)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		serveFiles = storageConfig.Type == "local" && storageConfig.BaseURL == ""
+		if serveFiles {
+			storageConfig.BaseURL = "/files"
+		}
+		objectStore, err = storage.New(storageConfig)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		if *storageRetention > 0 {
+			go cleanupStorage(*storageRetention)
+		}
+		log.Printf("已开启截图存储: %s", storageConfig.Type)
+	}
+
+	// 启动浏览器池
+	pool, err = screenshot.NewPool(poolOptions)
+	if err != nil {
+		log.Fatalf("浏览器池启动失败: %v", err)
+	}
+	defer pool.Close()
+
+	// 打开异步任务队列，重启前未完成的任务会继续执行
+	jobQueue, err = jobs.Open(*jobsDir, jobOptions)
+	if err != nil {
+		log.Fatalf("任务队列启动失败: %v", err)
+	}
+	defer jobQueue.Close()
+	if n := jobQueue.Pending(); n > 0 {
+		log.Printf("继续执行 %d 个未完成的任务", n)
+	}
+
+	// 打开API密钥库，未指定时不校验密钥
+	protect := func(h http.HandlerFunc) http.HandlerFunc { return h }
+	if *keysFile != "" {
+		keys, err := apikey.Open(*keysFile)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		defer keys.Close()
+		protect = keys.Require
+
+		if *adminToken != "" {
+			http.Handle("/admin/", apikey.AdminHandler(keys, *adminToken))
+		} else {
+			log.Printf("未设置管理令牌，密钥管理接口不可用")
+		}
+	} else {
+		log.Printf("警告: 未指定 -keys，截图接口不需要API密钥")
+	}
+
 	// 设置HTTP路由
-	http.HandleFunc("/screenshot", handleScreenshot)
-	http.HandleFunc("/screenshot/info", handleScreenshotInfo)
+	http.HandleFunc("/screenshot", protect(handleScreenshot))
+	http.HandleFunc("/screenshot/info", protect(handleScreenshotInfo))
+	http.HandleFunc("/screenshot/elements", protect(handleScreenshotElements))
+	http.HandleFunc("POST /screenshot/steps", protect(handleScreenshotSteps))
+	http.HandleFunc("POST /compare", protect(handleCompare))
+	http.HandleFunc("POST /jobs", protect(handleCreateJob))
+	http.HandleFunc("GET /jobs/{id}", protect(handleJobStatus))
+	http.HandleFunc("GET /jobs/{id}/result", protect(handleJobResult))
+	if serveFiles {
+		// 保存的截图同样需要API密钥，命名模板可能不含随机ID，文件名可以被猜到
+		http.HandleFunc("GET /files/", protect(http.StripPrefix("/files/", http.FileServer(http.Dir(storageConfig.Dir))).ServeHTTP))
+	}
 	
 	// 启动HTTP服务器
-	port := 8080
-	fmt.Printf("截图服务启动于 http://localhost:%d\n", port)
-	fmt.Printf("- 截图API: http://localhost:%d/screenshot?url=网址\n", port)
-	fmt.Printf("- 信息API: http://localhost:%d/screenshot/info?url=网址\n", port)
+	fmt.Printf("截图服务启动于 http://localhost:%d（%d 个浏览器，最多 %d 个并发截图）\n",
+		*port, poolOptions.Browsers, poolOptions.MaxConcurrency)
+	fmt.Printf("- 截图API: http://localhost:%d/screenshot?url=网址\n", *port)
+	fmt.Printf("- 信息API: http://localhost:%d/screenshot/info?url=网址（加 &har=1 下载HAR文件）\n", *port)
+	fmt.Printf("- 元素API: http://localhost:%d/screenshot/elements?url=网址&selector=选择器\n", *port)
+	fmt.Printf("- 交互API: POST http://localhost:%d/screenshot/steps\n", *port)
+	fmt.Printf("- 比较API: POST http://localhost:%d/compare（上传 baseline 图片）\n", *port)
+	fmt.Printf("- 任务API: POST http://localhost:%d/jobs?url=网址，GET /jobs/{id} 查询状态\n", *port)
+	if objectStore != nil {
+		fmt.Printf("- 保存截图: http://localhost:%d/screenshot?url=网址&store=1（返回对象的键和地址）\n", *port)
+	}
+	if *keysFile != "" && *adminToken != "" {
+		fmt.Printf("- 密钥管理: http://localhost:%d/admin/keys\n", *port)
+	}
 	
-	if err := http.ListenAndServe(fmt.Sprintf(":%d", port), nil); err != nil {
+	// 收到中断信号后停止接收请求，等待正在处理的请求结束，再由 defer 关闭任务队列和浏览器池
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	server := &http.Server{Addr: fmt.Sprintf(":%d", *port)}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- server.ListenAndServe() }()
+	select {
+	case err := <-serveErr:
 		log.Fatalf("服务器启动失败: %v", err)
+	case <-ctx.Done():
+	}
+
+	log.Printf("正在关闭服务...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("等待请求结束超时: %v", err)
 	}
 }
 
 func handleScreenshot(w http.ResponseWriter, r *http.Request) {
-	// 获取URL参数
-	url := r.URL.Query().Get("url")
-	if url == "" {
-		http.Error(w, "请提供有效的URL参数", http.StatusBadRequest)
+	// 获取URL和截图选项
+	url, options, err := readRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	// 设置截图选项
-	options := parseOptions(r)
 	
 	// 记录开始时间
 	startTime := time.Now()
 	
-	// 捕获截图
-	buf, timing, err := screenshot.CaptureScreenshot(url, options)
+	// 捕获截图，开启缓存时相同的请求直接返回缓存，同时到达的相同请求共用一次截图
+	var timing *screenshot.TimingInfo
+	capture := func() (*cache.Entry, error) {
+		result, err := pool.Capture(url, options)
+		if err != nil {
+			return nil, err
+		}
+		timing = &result.Timing
+		return cache.NewEntry(outputImage(result), options.Format.ContentType(), options.Format.Extension()), nil
+	}
+	var entry *cache.Entry
+	status := cache.Miss
+	if resultCache != nil {
+		fresh := r.URL.Query().Get("fresh")
+		entry, status, err = resultCache.Do(screenshot.CacheKey(url, options), fresh == "1" || fresh == "true", capture)
+	} else {
+		entry, err = capture()
+	}
 	if err != nil {
 		log.Printf("无法获取截图: %v", err)
 		http.Error(w, fmt.Sprintf("截图失败: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	// 添加耗时统计到响应头
-	w.Header().Set("X-Timing-Total", fmt.Sprintf("%.2fs", timing.TotalTime.Seconds()))
-	w.Header().Set("X-Timing-Browser", fmt.Sprintf("%.2fs", timing.BrowserStart.Seconds()))
-	w.Header().Set("X-Timing-Navigation", fmt.Sprintf("%.2fs", timing.Navigation.Seconds()))
-	w.Header().Set("X-Timing-Screenshot", fmt.Sprintf("%.2fs", timing.ScreenshotTime.Seconds()))
+	// 添加耗时统计到响应头，命中缓存时没有耗时统计
+	if timing != nil {
+		w.Header().Set("X-Timing-Total", fmt.Sprintf("%.2fs", timing.TotalTime.Seconds()))
+		w.Header().Set("X-Timing-Browser", fmt.Sprintf("%.2fs", timing.BrowserStart.Seconds()))
+		w.Header().Set("X-Timing-Navigation", fmt.Sprintf("%.2fs", timing.Navigation.Seconds()))
+		w.Header().Set("X-Timing-TTFB", fmt.Sprintf("%.3fs", timing.TTFB.Seconds()))
+		w.Header().Set("X-Timing-Load", fmt.Sprintf("%.2fs", timing.Load.Seconds()))
+		w.Header().Set("X-Timing-Ready", fmt.Sprintf("%.2fs", timing.WaitComplete.Seconds()))
+		w.Header().Set("X-Timing-Screenshot", fmt.Sprintf("%.2fs", timing.ScreenshotTime.Seconds()))
+		if timing.WaitTimedOut {
+			w.Header().Set("X-Wait-Timed-Out", "true")
+		}
+		w.Header().Set("X-Blocked-Requests", strconv.Itoa(timing.BlockedResources+timing.BlockedByList+timing.BlockedByPolicy))
+	}
+	if resultCache != nil {
+		w.Header().Set("X-Cache", string(status))
+		w.Header().Set("Age", strconv.Itoa(int(time.Since(entry.Created).Seconds())))
+	}
+	if store := r.URL.Query().Get("store"); store == "1" || store == "true" {
+		storeResult(w, url, entry, timing, status)
+		return
+	}
+	w.Header().Set("ETag", entry.ETag)
+	if match := r.Header.Get("If-None-Match"); match != "" && cache.MatchETag(match, entry.ETag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 	
 	// 设置响应头并返回图片
-	w.Header().Set("Content-Type", "image/png")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=screenshot-%d.png", time.Now().Unix()))
-	w.Write(buf)
+	w.Header().Set("Content-Type", entry.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=screenshot-%d.%s", time.Now().Unix(), entry.Extension))
+	w.Write(entry.Data)
 	
 	// 记录请求完成信息
-	log.Printf("截图完成: %s (耗时: %.2fs, 大小: %d KB)", 
+	log.Printf("截图完成: %s (耗时: %.2fs, 大小: %d KB, 缓存: %s)", 
 		url, 
 		time.Since(startTime).Seconds(),
-		len(buf)/1024,
+		len(entry.Data)/1024,
+		status,
 	)
 }
 
 func handleScreenshotInfo(w http.ResponseWriter, r *http.Request) {
-	// 获取URL参数
-	url := r.URL.Query().Get("url")
-	if url == "" {
-		sendJSONError(w, "请提供有效的URL参数", http.StatusBadRequest)
+	// 获取URL和截图选项
+	url, options, err := readRequest(r)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	// 设置截图选项
-	options := parseOptions(r)
+	options.Diagnostics = true
 	
 	// 捕获截图
-	_, timing, err := screenshot.CaptureScreenshot(url, options)
+	result, err := pool.Capture(url, options)
 	if err != nil {
 		log.Printf("无法获取截图信息: %v", err)
 		sendJSONError(w, fmt.Sprintf("获取信息失败: %v", err), http.StatusInternalServerError)
 		return
 	}
+	timing := result.Timing
 
-	// 返回耗时统计信息
+	// 以HAR文件下载
+	if har := r.URL.Query().Get("har"); har == "1" || har == "true" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=screenshot-%d.har", time.Now().Unix()))
+		json.NewEncoder(w).Encode(result.Diagnostics.HAR)
+		return
+	}
+
+	// 返回耗时统计和诊断信息
 	w.Header().Set("Content-Type", "application/json")
 	response := Response{
-		Success: true,
-		Timing:  screenshot.TimingToMap(timing),
+		Success:     true,
+		Timing:      screenshot.TimingToMap(timing),
+		Diagnostics: result.Diagnostics,
+	}
+	if result.Thumbnail != nil {
+		response.Thumbnail = "data:" + options.Format.ContentType() + ";base64," + base64.StdEncoding.EncodeToString(result.Thumbnail)
 	}
 	
 	json.NewEncoder(w).Encode(response)
 }
 
-func parseOptions(r *http.Request) screenshot.Options {
+// handleScreenshotElements 截取选择器匹配的所有元素，以zip压缩包返回
+func handleScreenshotElements(w http.ResponseWriter, r *http.Request) {
+	url, options, err := readRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	selector := r.URL.Query().Get("selector")
+	if selector == "" {
+		http.Error(w, "请提供有效的selector参数", http.StatusBadRequest)
+		return
+	}
+
+	options.Element = selector
+	options.AllElements = true
+	options.MaxElements = maxElements
+	// selector参数在这里表示要截取的元素，不再用于等待
+	options.Selector = ""
+
+	result, err := pool.Capture(url, options)
+	if errors.Is(err, screenshot.ErrTooManyElements) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("无法获取元素截图: %v", err)
+		http.Error(w, fmt.Sprintf("截图失败: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("X-Element-Count", strconv.Itoa(len(result.Shots)))
+	writeZip(w, result.Shots, fmt.Sprintf("elements-%d.zip", time.Now().Unix()))
+
+	log.Printf("元素截图完成: %s (%d 个元素, 耗时: %.2fs)", url, len(result.Shots), result.Timing.TotalTime.Seconds())
+}
+
+// readRequest 从查询参数读取URL和截图选项，POST请求的JSON请求体可以提供URL和认证信息
+func readRequest(r *http.Request) (string, screenshot.Options, error) {
+	url := r.URL.Query().Get("url")
+	options, err := parseOptions(r)
+	if err != nil {
+		return "", options, err
+	}
+
+	// multipart请求体用于上传图片（如视觉比较），不包含截图选项
+	if r.Method == http.MethodPost && !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		var req captureRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+			return "", options, fmt.Errorf("无效的JSON请求体: %w", err)
+		}
+		if req.URL != "" {
+			url = req.URL
+		}
+		options.Headers = req.Headers
+		options.Cookies = req.Cookies
+		options.BasicAuth = req.BasicAuth
+		options.LocalStorage = req.LocalStorage
+		options.SessionStorage = req.SessionStorage
+		if err := screenshot.ValidateSteps(req.Steps); err != nil {
+			return "", options, err
+		}
+		options.Steps = req.Steps
+		if req.CSS != "" {
+			options.CSS = req.CSS
+		}
+		if req.Script != "" {
+			options.Script = req.Script
+		}
+	}
+
+	if url == "" {
+		return "", options, errors.New("请提供有效的URL参数")
+	}
+	if options.MaxHeight > maxHeightLimit {
+		return "", options, fmt.Errorf("max-height 不能超过 %d", maxHeightLimit)
+	}
+	if options.MaxHeight == 0 {
+		options.MaxHeight = maxHeightLimit
+	}
+	if err := urlPolicy.Check(r.Context(), url); err != nil {
+		return "", options, err
+	}
+	options.URLPolicy = urlPolicy
+	return url, options, nil
+}
+
+// splitList 拆分逗号分隔的列表，忽略空项
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// handleScreenshotSteps 执行JSON请求体中的交互步骤，
+// 以zip压缩包返回步骤中截取的图片和最终的截图
+func handleScreenshotSteps(w http.ResponseWriter, r *http.Request) {
+	url, options, err := readRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(options.Steps) == 0 {
+		http.Error(w, "请在请求体中提供steps", http.StatusBadRequest)
+		return
+	}
+
+	result, err := pool.Capture(url, options)
+	if err != nil {
+		log.Printf("无法执行交互截图: %v", err)
+		http.Error(w, fmt.Sprintf("截图失败: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	shots := append(result.Shots, screenshot.Shot{
+		Name:  "final." + options.Format.Extension(),
+		Image: result.Image,
+	})
+	writeZip(w, shots, fmt.Sprintf("steps-%d.zip", time.Now().Unix()))
+
+	log.Printf("交互截图完成: %s (%d 个步骤, %d 张截图, 耗时: %.2fs)", url, len(options.Steps), len(shots), result.Timing.TotalTime.Seconds())
+}
+
+// CompareResponse 视觉比较的JSON响应
+type CompareResponse struct {
+	Success         bool             `json:"success"`
+	Passed          bool             `json:"passed"` // 差异不超过 max-mismatch
+	MismatchPercent float64          `json:"mismatch_percent"`
+	DiffPixels      int              `json:"diff_pixels"`
+	ChangedPixels   int              `json:"changed_pixels"`
+	AAPixels        int              `json:"aa_pixels"`
+	TotalPixels     int              `json:"total_pixels"`
+	SizeMismatch    bool             `json:"size_mismatch"`
+	DiffBounds      *screenshot.Clip `json:"diff_bounds,omitempty"`
+}
+
+// handleCompare 比较截图与基准图片。multipart请求体中的 baseline 为基准图片，
+// current 为要比较的图片；没有 current 时按查询参数截取 url 指定的页面。
+// 默认返回差异图，output=json 时只返回比较结果
+func handleCompare(w http.ResponseWriter, r *http.Request) {
+	// 最多上传两张图片，另外留出表单字段的空间
+	r.Body = http.MaxBytesReader(w, r.Body, 2*maxUploadSize+1<<20)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			sendJSONError(w, errUploadTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		sendJSONError(w, fmt.Sprintf("请使用multipart/form-data上传图片: %v", err), http.StatusBadRequest)
+		return
+	}
+	baselineData, err := formFile(r, "baseline")
+	if err != nil {
+		sendJSONError(w, err.Error(), uploadStatus(err))
+		return
+	}
+	if baselineData == nil {
+		sendJSONError(w, "请上传基准图片 baseline", http.StatusBadRequest)
+		return
+	}
+	currentData, err := formFile(r, "current")
+	if err != nil {
+		sendJSONError(w, err.Error(), uploadStatus(err))
+		return
+	}
+
+	query := r.URL.Query()
+	options := diff.DefaultOptions()
+	if t, err := strconv.ParseFloat(query.Get("threshold"), 64); err == nil && t >= 0 && t <= 1 {
+		options.Threshold = t
+	}
+	options.IncludeAA = query.Get("include-aa") == "1" || query.Get("include-aa") == "true"
+	for _, value := range query["ignore"] {
+		rect, err := diff.ParseRect(value)
+		if err != nil {
+			sendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		options.Ignore = append(options.Ignore, rect)
+	}
+	selectors := query["ignore-selector"]
+
+	if currentData == nil {
+		// 截取页面，同时记录需要忽略的元素位置
+		url, shotOptions, err := readRequest(r)
+		if err != nil {
+			sendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		shotOptions.Format = screenshot.FormatPNG
+		shotOptions.Locate = selectors
+		// 与基准图片逐像素比较，不做截图后处理，忽略区域的位置也按原始截图计算
+		shotOptions.Crop, shotOptions.Resize, shotOptions.Thumbnail = nil, nil, nil
+		shotOptions.Watermark, shotOptions.OptimizePNG = false, false
+		result, err := pool.Capture(url, shotOptions)
+		if err != nil {
+			log.Printf("无法获取比较截图: %v", err)
+			sendJSONError(w, fmt.Sprintf("截图失败: %v", err), http.StatusInternalServerError)
+			return
+		}
+		currentData = result.Image
+		for _, region := range result.Regions {
+			options.Ignore = append(options.Ignore, diff.Rect(region.X, region.Y, region.Width, region.Height))
+		}
+	} else if len(selectors) > 0 {
+		sendJSONError(w, "ignore-selector 只能在截取页面时使用", http.StatusBadRequest)
+		return
+	}
+
+	baseline, err := diff.Decode(baselineData)
+	if err != nil {
+		sendJSONError(w, "基准图片: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	current, err := diff.Decode(currentData)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result := diff.Compare(current, baseline, options)
+
+	var maxMismatch float64
+	if m, err := strconv.ParseFloat(query.Get("max-mismatch"), 64); err == nil && m >= 0 {
+		maxMismatch = m
+	}
+	passed := result.MismatchPercent <= maxMismatch
+
+	w.Header().Set("X-Mismatch-Percent", strconv.FormatFloat(result.MismatchPercent, 'f', 3, 64))
+	w.Header().Set("X-Diff-Pixels", strconv.Itoa(result.DiffPixels))
+	w.Header().Set("X-Total-Pixels", strconv.Itoa(result.TotalPixels))
+	w.Header().Set("X-Compare-Passed", strconv.FormatBool(passed))
+
+	if query.Get("output") == "json" {
+		response := CompareResponse{
+			Success:         true,
+			Passed:          passed,
+			MismatchPercent: result.MismatchPercent,
+			DiffPixels:      result.DiffPixels,
+			ChangedPixels:   result.ChangedPixels,
+			AAPixels:        result.AAPixels,
+			TotalPixels:     result.TotalPixels,
+			SizeMismatch:    result.SizeMismatch,
+		}
+		if b := result.Bounds; !b.Empty() {
+			response.DiffBounds = &screenshot.Clip{X: float64(b.Min.X), Y: float64(b.Min.Y), Width: float64(b.Dx()), Height: float64(b.Dy())}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	data, err := diff.EncodePNG(result.Image)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=diff-%d.png", time.Now().Unix()))
+	w.Write(data)
+
+	log.Printf("视觉比较完成: 差异 %.3f%% (%d 像素)", result.MismatchPercent, result.DiffPixels)
+}
+
+// 比较API每张上传图片的大小上限
+const maxUploadSize = 32 << 20
+
+var errUploadTooLarge = fmt.Errorf("上传的图片过大，每张不能超过 %d MB", maxUploadSize>>20)
+
+// uploadStatus 返回读取上传文件出错时的状态码
+func uploadStatus(err error) int {
+	if errors.Is(err, errUploadTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// formFile 读取上传的文件，没有该字段时返回 nil，超过 maxUploadSize 时返回错误
+func formFile(r *http.Request, name string) ([]byte, error) {
+	f, _, err := r.FormFile(name)
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取上传的 %s 失败: %w", name, err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxUploadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取上传的 %s 失败: %w", name, err)
+	}
+	if len(data) > maxUploadSize {
+		return nil, fmt.Errorf("%s: %w", name, errUploadTooLarge)
+	}
+	return data, nil
+}
+
+// handleCreateJob 提交异步截图任务，立即返回任务ID，
+// 参数与截图API相同，callback_url 可以通过查询参数或JSON请求体指定
+func handleCreateJob(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		sendJSONError(w, fmt.Sprintf("读取请求体失败: %v", err), http.StatusBadRequest)
+		return
+	}
+	// API密钥不随任务保存
+	query := r.URL.Query()
+	query.Del("api_key")
+	req := jobs.Request{Query: query.Encode()}
+	if len(bytes.TrimSpace(body)) > 0 {
+		req.Body = body
+	}
+
+	// 提交时先解析一次，参数错误的任务直接拒绝
+	jr, err := jobRequest(r.Context(), req)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	url, _, err := readRequest(jr)
+	if err == nil {
+		_, err = jobTimeoutFor(jr)
+	}
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	callbackURL := r.URL.Query().Get("callback_url")
+	if req.Body != nil {
+		var cr captureRequest
+		json.Unmarshal(req.Body, &cr)
+		if cr.CallbackURL != "" {
+			callbackURL = cr.CallbackURL
+		}
+	}
+	if callbackURL != "" {
+		if err := urlPolicy.Check(r.Context(), callbackURL); err != nil {
+			sendJSONError(w, fmt.Sprintf("回调URL不允许访问: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	owner := ""
+	if info := apikey.FromContext(r.Context()); info != nil {
+		owner = info.ID
+	}
+	job, err := jobQueue.Enqueue(url, callbackURL, owner, req)
+	if errors.Is(err, jobs.ErrQueueFull) {
+		w.Header().Set("Retry-After", "30")
+		sendJSONError(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("已提交任务 %s: %s", job.ID, url)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// ownsJob 检查请求的API密钥是否为提交任务的密钥，未开启密钥校验时不检查
+func ownsJob(r *http.Request, job *jobs.Job) bool {
+	info := apikey.FromContext(r.Context())
+	return info == nil || info.ID == job.Owner
+}
+
+// handleJobStatus 返回任务状态，只有提交任务的API密钥可以查询
+func handleJobStatus(w http.ResponseWriter, r *http.Request) {
+	job, err := jobQueue.Get(r.PathValue("id"))
+	if err == nil && !ownsJob(r, job) {
+		err = jobs.ErrNotFound
+	}
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// handleJobResult 下载已完成任务的截图
+func handleJobResult(w http.ResponseWriter, r *http.Request) {
+	f, job, err := jobQueue.OpenResult(r.PathValue("id"))
+	if job != nil && !ownsJob(r, job) {
+		if f != nil {
+			f.Close()
+		}
+		err = jobs.ErrNotFound
+	}
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		sendJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, jobs.ErrNotReady):
+		sendJSONError(w, fmt.Sprintf("%v（当前状态: %s）", err, job.Status), http.StatusConflict)
+		return
+	case err != nil:
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	name := filepath.Base(f.Name())
+	w.Header().Set("Content-Type", job.ContentType)
+	w.Header().Set("Content-Disposition", "attachment; filename=screenshot-"+name)
+	http.ServeContent(w, r, name, *job.FinishedAt, f)
+}
+
+// jobRequest 把保存的任务请求还原为HTTP请求，以便复用 readRequest 解析参数
+func jobRequest(ctx context.Context, req jobs.Request) (*http.Request, error) {
+	method, body := http.MethodGet, io.Reader(http.NoBody)
+	if len(req.Body) > 0 {
+		method, body = http.MethodPost, bytes.NewReader(req.Body)
+	}
+	r, err := http.NewRequestWithContext(ctx, method, "/jobs?"+req.Query, body)
+	if err != nil {
+		return nil, fmt.Errorf("无效的任务请求: %w", err)
+	}
+	return r, nil
+}
+
+// jobTimeoutFor 返回任务的截图超时时间，timeout 参数为秒数，不能超过 -job-timeout
+func jobTimeoutFor(r *http.Request) (time.Duration, error) {
+	value := r.URL.Query().Get("timeout")
+	if value == "" {
+		return jobTimeout, nil
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		return 0, fmt.Errorf("无效的超时时间: %s", value)
+	}
+	timeout := time.Duration(seconds) * time.Second
+	if timeout > jobTimeout {
+		return 0, fmt.Errorf("超时时间不能超过 %d 秒", int(jobTimeout.Seconds()))
+	}
+	return timeout, nil
+}
+
+// runJob 执行异步截图任务，执行前重新按URL策略检查
+func runJob(ctx context.Context, req jobs.Request) (*jobs.Result, error) {
+	r, err := jobRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	url, options, err := readRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	if options.Timeout, err = jobTimeoutFor(r); err != nil {
+		return nil, err
+	}
+
+	result, err := pool.CaptureContext(ctx, url, options)
+	if err != nil {
+		log.Printf("任务截图失败: %s: %v", url, err)
+		return nil, fmt.Errorf("截图失败: %w", err)
+	}
+	data := outputImage(result)
+	log.Printf("任务截图完成: %s (耗时: %.2fs, 大小: %d KB)", url, result.Timing.TotalTime.Seconds(), len(data)/1024)
+
+	return &jobs.Result{
+		Data:        data,
+		ContentType: options.Format.ContentType(),
+		Extension:   options.Format.Extension(),
+	}, nil
+}
+
+// storeResult 保存截图并返回对象的键、地址和元数据
+func storeResult(w http.ResponseWriter, url string, entry *cache.Entry, timing *screenshot.TimingInfo, status cache.Status) {
+	if objectStore == nil {
+		sendJSONError(w, "未配置截图存储，请使用 -storage 启动服务", http.StatusBadRequest)
+		return
+	}
+	key, err := storeTemplate.Key(storage.Vars{URL: url, Extension: entry.Extension, Data: entry.Data, Time: time.Now()})
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	obj, err := objectStore.Put(context.Background(), key, entry.Data, entry.ContentType)
+	if err != nil {
+		log.Printf("保存截图失败: %s: %v", url, err)
+		sendJSONError(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	log.Printf("截图已保存: %s -> %s (大小: %d KB)", url, obj.Key, obj.Size/1024)
+
+	resp := StoredResponse{Success: true, Object: *obj, SourceURL: url}
+	if resultCache != nil {
+		resp.Cache = string(status)
+	}
+	if timing != nil {
+		resp.Timing = screenshot.TimingToMap(*timing)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// cleanupStorage 定期删除超过保留时间的截图
+func cleanupStorage(retention time.Duration) {
+	interval := min(retention/10, time.Hour)
+	for range time.Tick(max(interval, time.Minute)) {
+		n, err := storage.Cleanup(context.Background(), objectStore, "", time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("清理过期截图失败: %v", err)
+		}
+		if n > 0 {
+			log.Printf("已删除 %d 张过期截图", n)
+		}
+	}
+}
+
+// outputImage 返回响应中的图片，请求了缩略图时只返回缩略图
+func outputImage(result *screenshot.ScreenshotResult) []byte {
+	if result.Thumbnail != nil {
+		return result.Thumbnail
+	}
+	return result.Image
+}
+
+// writeZip 把多张图片打包为zip压缩包返回，重名的图片在扩展名前加序号
+func writeZip(w http.ResponseWriter, shots []screenshot.Shot, filename string) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	used := make(map[string]bool)
+	for _, shot := range shots {
+		name := shot.Name
+		ext := filepath.Ext(name)
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(shot.Name, ext), n, ext)
+		}
+		used[name] = true
+		f, err := zw.Create(name)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("打包失败: %v", err), http.StatusInternalServerError)
+			return
+		}
+		f.Write(shot.Image)
+	}
+	if err := zw.Close(); err != nil {
+		http.Error(w, fmt.Sprintf("打包失败: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	w.Write(buf.Bytes())
+}
+
+func parseOptions(r *http.Request) (screenshot.Options, error) {
 	options := screenshot.DefaultOptions()
 	
 	// 处理附加参数
@@ -124,14 +961,89 @@ func parseOptions(r *http.Request) screenshot.Options {
 		options.FullPage = true
 	}
 	
+	if maxHeight := r.URL.Query().Get("max-height"); maxHeight != "" {
+		if h, err := strconv.Atoi(maxHeight); err == nil && h > 0 {
+			options.MaxHeight = h
+		}
+	}
+	
+	// 滚动页面触发懒加载，任一滚动参数都会开启滚动和全页面截图
+	if query := r.URL.Query(); query.Has("scroll") || query.Has("scroll-step") || query.Has("scroll-delay") || query.Has("hide-fixed") {
+		if scroll := query.Get("scroll"); scroll == "" || scroll == "1" || scroll == "true" {
+			s := screenshot.DefaultScrollOptions()
+			if step, err := strconv.Atoi(query.Get("scroll-step")); err == nil && step > 0 {
+				s.Step = step
+			}
+			if delay, err := strconv.Atoi(query.Get("scroll-delay")); err == nil && delay >= 0 && delay <= 5000 {
+				s.Delay = time.Duration(delay) * time.Millisecond
+			}
+			s.HideFixed = query.Get("hide-fixed") == "1" || query.Get("hide-fixed") == "true"
+			options.Scroll = &s
+			options.FullPage = true
+		}
+	}
+	
 	if mobile := r.URL.Query().Get("mobile"); mobile == "1" || mobile == "true" {
 		options.MobileMode = true
 	}
 	
+	if device := r.URL.Query().Get("device"); device != "" {
+		if d, err := screenshot.LookupDevice(device); err == nil {
+			options.Device = d
+		}
+	}
+	
+	if orientation := r.URL.Query().Get("orientation"); orientation == "landscape" {
+		options.Landscape = true
+	}
+	
+	if dpr := r.URL.Query().Get("dpr"); dpr != "" {
+		if d, err := strconv.ParseFloat(dpr, 64); err == nil && d > 0 && d <= 4 {
+			options.DeviceScaleFactor = d
+		}
+	}
+	
 	if userAgent := r.URL.Query().Get("ua"); userAgent != "" {
 		options.UserAgent = userAgent
 	}
 	
+	// 渲染环境
+	if scheme := r.URL.Query().Get("color-scheme"); scheme != "" {
+		if s, err := screenshot.ParseColorScheme(scheme); err == nil {
+			options.ColorScheme = s
+		}
+	}
+	
+	if reducedMotion := r.URL.Query().Get("reduced-motion"); reducedMotion == "1" || reducedMotion == "true" {
+		options.ReducedMotion = true
+	}
+	
+	if media := r.URL.Query().Get("media"); media != "" {
+		if m, err := screenshot.ParseMediaType(media); err == nil {
+			options.Media = m
+		}
+	}
+	
+	if locale := r.URL.Query().Get("locale"); locale != "" {
+		if l, err := screenshot.ParseLocale(locale); err == nil {
+			options.Locale = l
+		}
+	}
+	
+	if timezone := r.URL.Query().Get("timezone"); timezone != "" {
+		options.Timezone = timezone
+	}
+	
+	if geolocation := r.URL.Query().Get("geolocation"); geolocation != "" {
+		if g, err := screenshot.ParseGeolocation(geolocation); err == nil {
+			options.Geolocation = g
+		}
+	}
+	
+	if animations := r.URL.Query().Get("disable-animations"); animations == "1" || animations == "true" {
+		options.DisableAnimations = true
+	}
+	
 	if wait := r.URL.Query().Get("wait"); wait != "" {
 		if waitTime, err := strconv.Atoi(wait); err == nil && waitTime > 0 {
 			options.WaitTime = time.Duration(waitTime) * time.Second
@@ -146,11 +1058,141 @@ func parseOptions(r *http.Request) screenshot.Options {
 		options.BlockJS = true
 	}
 	
+	if block := r.URL.Query().Get("block"); block != "" {
+		if types, err := screenshot.ParseResourceTypes(block); err == nil {
+			options.BlockResources = types
+		}
+	}
+	
+	if blockAds := r.URL.Query().Get("block-ads"); blockAds == "1" || blockAds == "true" {
+		options.Blocklist = blocklist
+	}
+	
 	if selector := r.URL.Query().Get("selector"); selector != "" {
 		options.Selector = selector
 	}
 	
-	return options
+	// 事件驱动的等待
+	if waitUntil := r.URL.Query().Get("wait-until"); waitUntil != "" {
+		if events, err := screenshot.ParseWaitEvents(waitUntil); err == nil {
+			options.WaitUntil = events
+		}
+	}
+	
+	if waitFor := r.URL.Query().Get("wait-for"); waitFor != "" {
+		options.WaitExpression = waitFor
+	}
+	
+	if idle := r.URL.Query().Get("network-idle"); idle != "" {
+		if ms, err := strconv.Atoi(idle); err == nil && ms > 0 {
+			options.NetworkIdle = time.Duration(ms) * time.Millisecond
+		}
+	}
+	
+	if deadline := r.URL.Query().Get("wait-deadline"); deadline != "" {
+		if d, err := strconv.Atoi(deadline); err == nil && d > 0 {
+			options.WaitDeadline = time.Duration(d) * time.Second
+		}
+	}
+	
+	// 截图前修改页面，hide 和 remove 可以重复指定
+	options.CSS = r.URL.Query().Get("css")
+	options.Script = r.URL.Query().Get("script")
+	options.Hide = r.URL.Query()["hide"]
+	options.Remove = r.URL.Query()["remove"]
+	if consent := r.URL.Query().Get("hide-consent"); consent == "1" || consent == "true" {
+		options.HideConsent = true
+	}
+	
+	// 元素或区域截图
+	if element := r.URL.Query().Get("element"); element != "" {
+		options.Element = element
+	}
+	
+	if padding := r.URL.Query().Get("padding"); padding != "" {
+		if p, err := strconv.Atoi(padding); err == nil && p >= 0 {
+			options.Padding = p
+		}
+	}
+	
+	if clip := r.URL.Query().Get("clip"); clip != "" {
+		c, err := screenshot.ParseClip(clip)
+		if err != nil {
+			return options, err
+		}
+		options.Clip = c
+	}
+	
+	if format := r.URL.Query().Get("format"); format != "" {
+		if f, err := screenshot.ParseFormat(format); err == nil {
+			options.Format = f
+		}
+	}
+	
+	if quality := r.URL.Query().Get("quality"); quality != "" {
+		if q, err := strconv.Atoi(quality); err == nil && q > 0 && q <= 100 {
+			options.Quality = q
+		}
+	}
+	
+	// 截图后处理
+	if crop := r.URL.Query().Get("crop"); crop != "" {
+		if c, err := screenshot.ParseClip(crop); err == nil {
+			options.Crop = c
+		}
+	}
+	
+	if size := r.URL.Query().Get("resize"); size != "" {
+		if fit, err := screenshot.ParseFitMode(r.URL.Query().Get("fit"), screenshot.FitInside); err == nil {
+			if rs, err := screenshot.ParseResize(size, fit); err == nil {
+				options.Resize = rs
+			}
+		}
+	}
+	
+	if thumbnail := r.URL.Query().Get("thumbnail"); thumbnail != "" {
+		if t, err := screenshot.ParseResize(thumbnail, screenshot.FitCover); err == nil {
+			options.Thumbnail = t
+		}
+	}
+	
+	if watermark := r.URL.Query().Get("watermark"); watermark == "1" || watermark == "true" {
+		options.Watermark = true
+	}
+	
+	if optimize := r.URL.Query().Get("optimize"); optimize == "1" || optimize == "true" {
+		options.OptimizePNG = true
+	}
+	
+	// PDF页面设置
+	if paper := r.URL.Query().Get("paper"); paper != "" {
+		options.PDF.SetPaper(paper)
+	}
+	
+	if landscape := r.URL.Query().Get("landscape"); landscape == "1" || landscape == "true" {
+		options.PDF.Landscape = true
+	}
+	
+	if margin := r.URL.Query().Get("margin"); margin != "" {
+		if m, err := strconv.ParseFloat(margin, 64); err == nil && m >= 0 {
+			options.PDF.MarginTop, options.PDF.MarginBottom = m, m
+			options.PDF.MarginLeft, options.PDF.MarginRight = m, m
+		}
+	}
+	
+	if background := r.URL.Query().Get("background"); background != "" {
+		options.PDF.PrintBackground = background == "1" || background == "true"
+	}
+	
+	if header := r.URL.Query().Get("header"); header != "" {
+		options.PDF.HeaderTemplate = header
+	}
+	
+	if footer := r.URL.Query().Get("footer"); footer != "" {
+		options.PDF.FooterTemplate = footer
+	}
+	
+	return options, options.ValidateRegion()
 }
 
 func sendJSONError(w http.ResponseWriter, errorMsg string, statusCode int) {
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Local 把对象保存为本地目录中的文件，键中的 / 对应子目录
+type Local struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocal 创建本地存储，目录不存在时创建
+func NewLocal(dir, baseURL string) (*Local, error) {
+	if dir == "" {
+		return nil, errors.New("请指定本地存储的目录")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建存储目录失败: %w", err)
+	}
+	return &Local{dir: dir, baseURL: baseURL}, nil
+}
+
+// Dir 返回存储目录
+func (l *Local) Dir() string {
+	return l.dir
+}
+
+func (l *Local) path(key string) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(key)), nil
+}
+
+// Put 先写临时文件再重命名，避免读到写了一半的文件
+func (l *Local) Put(ctx context.Context, key string, data []byte, contentType string) (*Object, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("保存截图失败: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return nil, fmt.Errorf("保存截图失败: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, fmt.Errorf("保存截图失败: %w", err)
+	}
+
+	// 与S3一致，ETag为内容的MD5
+	sum := md5.Sum(data)
+	return &Object{
+		Key:         key,
+		URL:         objectURL(l.baseURL, key),
+		Size:        int64(len(data)),
+		ContentType: contentType,
+		ETag:        `"` + hex.EncodeToString(sum[:]) + `"`,
+		Created:     time.Now(),
+	}, nil
+}
+
+// Get 读取对象
+func (l *Local) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取截图失败: %w", err)
+	}
+	return data, nil
+}
+
+// Delete 删除对象，并删除因此变空的子目录
+func (l *Local) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("删除截图失败: %w", err)
+	}
+	root := filepath.Clean(l.dir)
+	for dir := filepath.Dir(path); dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// List 列出对象，创建时间为文件的修改时间
+func (l *Local) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	err := filepath.WalkDir(l.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(path, ".tmp") {
+			return nil
+		}
+		rel, err := filepath.Rel(l.dir, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{
+			Key:     key,
+			URL:     objectURL(l.baseURL, key),
+			Size:    info.Size(),
+			Created: info.ModTime(),
+		})
+		return ctx.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("列出截图失败: %w", err)
+	}
+	return objects, nil
+}
@@ -0,0 +1,339 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Options S3兼容存储的配置
+type S3Options struct {
+	Endpoint     string // 如 https://s3.us-east-1.amazonaws.com 或 http://localhost:9000，默认为AWS的区域地址
+	Region       string // 默认为 us-east-1
+	Bucket       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string // 临时凭证的令牌，可选
+	PathStyle    bool   // 使用 endpoint/bucket/key 形式的地址（MinIO等需要），否则为 bucket.endpoint/key
+	PublicURL    string // 对象的公开访问地址前缀，为空时使用对象的S3地址
+	Client       *http.Client
+}
+
+// S3 把对象保存到S3兼容的对象存储，请求使用AWS签名V4
+type S3 struct {
+	options  S3Options
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// NewS3 创建S3存储
+func NewS3(options S3Options) (*S3, error) {
+	if options.Bucket == "" {
+		return nil, errors.New("请指定S3存储桶")
+	}
+	if options.AccessKey == "" || options.SecretKey == "" {
+		return nil, errors.New("请指定S3的访问密钥")
+	}
+	if options.Region == "" {
+		options.Region = "us-east-1"
+	}
+	if options.Endpoint == "" {
+		options.Endpoint = "https://s3." + options.Region + ".amazonaws.com"
+	}
+	endpoint, err := url.Parse(options.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("无效的S3地址: %s", options.Endpoint)
+	}
+	client := options.Client
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	return &S3{options: options, endpoint: endpoint, client: client}, nil
+}
+
+// url 返回对象（key为空时为存储桶）的地址
+func (s *S3) url(key string) *url.URL {
+	u := *s.endpoint
+	path := strings.TrimSuffix(u.Path, "/")
+	if s.options.PathStyle {
+		path += "/" + s.options.Bucket
+	} else {
+		u.Host = s.options.Bucket + "." + u.Host
+	}
+	u.Path = path + "/" + key
+	u.RawPath = ""
+	u.RawQuery = ""
+	return &u
+}
+
+// objectURL 返回对象的公开访问地址
+func (s *S3) objectURL(key string) string {
+	if s.options.PublicURL != "" {
+		return objectURL(s.options.PublicURL, key)
+	}
+	u := s.url("")
+	return u.Scheme + "://" + u.Host + escapePath(u.Path+key)
+}
+
+// Put 上传对象
+func (s *S3) Put(ctx context.Context, key string, data []byte, contentType string) (*Object, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(ctx, http.MethodPut, key, nil, header, data)
+	if err != nil {
+		return nil, fmt.Errorf("上传截图失败: %w", err)
+	}
+	resp.Body.Close()
+
+	return &Object{
+		Key:         key,
+		URL:         s.objectURL(key),
+		Size:        int64(len(data)),
+		ContentType: contentType,
+		ETag:        resp.Header.Get("ETag"),
+		Created:     time.Now(),
+	}, nil
+}
+
+// Get 下载对象
+func (s *S3) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("下载截图失败: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("下载截图失败: %w", err)
+	}
+	return data, nil
+}
+
+// Delete 删除对象，S3对不存在的对象同样返回成功
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("删除截图失败: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// listResult ListObjectsV2 的响应
+type listResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+		ETag         string    `xml:"ETag"`
+		Size         int64     `xml:"Size"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List 用 ListObjectsV2 分页列出对象，创建时间为对象的最后修改时间
+func (s *S3) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("列出截图失败: %w", err)
+		}
+		var result listResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("解析对象列表失败: %w", err)
+		}
+
+		for _, c := range result.Contents {
+			objects = append(objects, Object{
+				Key:     c.Key,
+				URL:     s.objectURL(c.Key),
+				Size:    c.Size,
+				ETag:    c.ETag,
+				Created: c.LastModified,
+			})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// s3Error S3的错误响应
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// do 发送签名后的请求，非2xx的响应转换为错误，404转换为 ErrNotFound
+func (s *S3) do(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	u := s.url(key)
+	u.RawPath = escapePath(u.Path)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.ContentLength = int64(len(body))
+	signV4(req, body, s.options, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && key != "" {
+		return nil, ErrNotFound
+	}
+	var e s3Error
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if xml.Unmarshal(data, &e) == nil && e.Code != "" {
+		return nil, fmt.Errorf("S3返回 %d %s: %s", resp.StatusCode, e.Code, e.Message)
+	}
+	return nil, fmt.Errorf("S3返回 %d", resp.StatusCode)
+}
+
+// signV4 按AWS签名V4签名请求，签名的请求头为 Host 和请求中已有的所有请求头
+func signV4(req *http.Request, body []byte, options S3Options, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := hashHex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if options.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", options.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.Join(strings.Fields(strings.Join(v, ",")), " ")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapePath(req.URL.Path),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + options.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hashHex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+options.SecretKey), date)
+	key = hmacSHA256(key, options.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+options.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// canonicalQuery 按键排序并按签名V4的规则转义查询参数
+func canonicalQuery(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, escape(k, false)+"="+escape(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapePath 按签名V4的规则转义路径，保留 /
+func escapePath(path string) string {
+	return escape(path, true)
+}
+
+// escape 除字母、数字和 -_.~ 外全部按 %XX 转义
+func escape(value string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (keepSlash && c == '/') {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,102 @@
+// Package storage 保存截图结果，支持本地目录和S3兼容的对象存储（AWS S3、MinIO等）
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("对象不存在")
+
+// Object 已保存的对象
+type Object struct {
+	Key         string    `json:"key"`
+	URL         string    `json:"url,omitempty"` // 公开访问地址，未配置时为空
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type,omitempty"`
+	ETag        string    `json:"etag,omitempty"`
+	Created     time.Time `json:"created"`
+}
+
+// Storage 截图的存储后端，键为 / 分隔的相对路径
+type Storage interface {
+	// Put 保存对象，已存在时覆盖
+	Put(ctx context.Context, key string, data []byte, contentType string) (*Object, error)
+	// Get 读取对象，不存在时返回 ErrNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete 删除对象，不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+	// List 列出键以 prefix 开头的所有对象
+	List(ctx context.Context, prefix string) ([]Object, error)
+}
+
+// Config 存储后端的配置
+type Config struct {
+	Type    string // local 或 s3
+	Dir     string // 本地存储的目录
+	BaseURL string // 对象的公开访问地址前缀，对象的URL为 BaseURL/键
+	S3      S3Options
+}
+
+// New 按配置创建存储后端
+func New(config Config) (Storage, error) {
+	switch strings.ToLower(config.Type) {
+	case "local":
+		return NewLocal(config.Dir, config.BaseURL)
+	case "s3":
+		options := config.S3
+		if options.PublicURL == "" {
+			options.PublicURL = config.BaseURL
+		}
+		return NewS3(options)
+	default:
+		return nil, fmt.Errorf("不支持的存储类型: %s（可用: local, s3）", config.Type)
+	}
+}
+
+// Cleanup 删除键以 prefix 开头、创建时间早于 before 的对象，返回删除的数量
+func Cleanup(ctx context.Context, s Storage, prefix string, before time.Time) (int, error) {
+	objects, err := s.List(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, obj := range objects {
+		if !obj.Created.Before(before) {
+			continue
+		}
+		if err := s.Delete(ctx, obj.Key); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// validateKey 检查键是否为安全的相对路径，不能跳出存储目录
+func validateKey(key string) error {
+	if key == "" || len(key) > 1024 {
+		return fmt.Errorf("无效的键: %q", key)
+	}
+	if strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("无效的键: %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("无效的键: %q", key)
+		}
+	}
+	return nil
+}
+
+// objectURL 拼接公开访问地址，键中的特殊字符按路径转义
+func objectURL(base, key string) string {
+	if base == "" {
+		return ""
+	}
+	return strings.TrimSuffix(base, "/") + "/" + escapePath(key)
+}
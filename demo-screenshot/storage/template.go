@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultTemplate 默认的命名模板
+const DefaultTemplate = "{date}/{host}-{id}.{ext}"
+
+// templateVars 命名模板中可以使用的变量
+var templateVars = map[string]string{
+	"id":   "随机ID",
+	"host": "截图页面的主机名",
+	"path": "截图页面的路径",
+	"date": "截图日期（UTC，2006-01-02）",
+	"time": "截图时间（UTC，150405）",
+	"unix": "截图时间的Unix时间戳",
+	"hash": "图片内容的哈希值",
+	"ext":  "文件扩展名",
+}
+
+// Vars 生成键所需的信息
+type Vars struct {
+	URL       string
+	Extension string // 不含点
+	Data      []byte
+	Time      time.Time
+}
+
+// Template 对象键的命名模板，如 "screenshots/{date}/{host}-{id}.{ext}"
+type Template struct {
+	parts []templatePart
+}
+
+// templatePart 模板中的一段文字或一个变量
+type templatePart struct {
+	text     string
+	variable bool
+}
+
+// ParseTemplate 解析命名模板，变量写在花括号中
+func ParseTemplate(value string) (*Template, error) {
+	t := &Template{}
+	rest := value
+	for rest != "" {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			t.parts = append(t.parts, templatePart{text: rest})
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("命名模板中的花括号不匹配: %s", value)
+		}
+		name := rest[start+1 : start+end]
+		if _, ok := templateVars[name]; !ok {
+			return nil, fmt.Errorf("命名模板中未知的变量 {%s}，可用: id, host, path, date, time, unix, hash, ext", name)
+		}
+		if start > 0 {
+			t.parts = append(t.parts, templatePart{text: rest[:start]})
+		}
+		t.parts = append(t.parts, templatePart{text: name, variable: true})
+		rest = rest[start+end+1:]
+	}
+
+	// 用示例值检查生成的键是否有效
+	if _, err := t.Key(Vars{URL: "https://example.com/", Extension: "png", Time: time.Now()}); err != nil {
+		return nil, fmt.Errorf("无效的命名模板 %s: %w", value, err)
+	}
+	return t, nil
+}
+
+// Key 生成对象键
+func (t *Template) Key(v Vars) (string, error) {
+	u, _ := url.Parse(v.URL)
+	if u == nil {
+		u = &url.URL{}
+	}
+	at := v.Time.UTC()
+
+	var b strings.Builder
+	for _, part := range t.parts {
+		if !part.variable {
+			b.WriteString(part.text)
+			continue
+		}
+		switch part.text {
+		case "id":
+			b.WriteString(randomID())
+		case "host":
+			b.WriteString(sanitize(strings.ToLower(u.Hostname()), "unknown"))
+		case "path":
+			b.WriteString(sanitize(strings.Trim(u.Path, "/"), "index"))
+		case "date":
+			b.WriteString(at.Format("2006-01-02"))
+		case "time":
+			b.WriteString(at.Format("150405"))
+		case "unix":
+			fmt.Fprintf(&b, "%d", at.Unix())
+		case "hash":
+			sum := sha256.Sum256(v.Data)
+			b.WriteString(hex.EncodeToString(sum[:8]))
+		case "ext":
+			b.WriteString(v.Extension)
+		}
+	}
+
+	key := b.String()
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+func randomID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// sanitize 只保留字母、数字、点、下划线和连字符，其他字符替换为连字符，最长100个字符
+func sanitize(value, empty string) string {
+	var b strings.Builder
+	for _, c := range value {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-' {
+			b.WriteRune(c)
+		} else {
+			b.WriteByte('-')
+		}
+	}
+	s := strings.Trim(b.String(), ".-")
+	if len(s) > 100 {
+		s = s[:100]
+	}
+	if s == "" {
+		return empty
+	}
+	return s
+}
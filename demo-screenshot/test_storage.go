@@ -0,0 +1,387 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fuwenhao/go-base/demo-screenshot/storage"
+)
+
+// 模拟的S3访问密钥
+const (
+	fakeAccessKey = "minioadmin"
+	fakeSecretKey = "minioadmin-secret"
+	fakeBucket    = "screenshots"
+	fakeRegion    = "us-east-1"
+)
+
+// 测试结果
+var failed int
+
+func check(name string, ok bool, detail ...any) {
+	if ok {
+		fmt.Printf("✓ %s\n", name)
+		return
+	}
+	failed++
+	fmt.Printf("✗ %s %v\n", name, fmt.Sprint(detail...))
+}
+
+func main() {
+	fmt.Println("=== 本地存储 ===")
+	testLocal()
+
+	fmt.Println("\n=== S3兼容存储（模拟的MinIO） ===")
+	testS3()
+
+	fmt.Println("\n=== 命名模板 ===")
+	testTemplate()
+
+	if failed > 0 {
+		fmt.Printf("\n%d 项测试失败\n", failed)
+		os.Exit(1)
+	}
+	fmt.Println("\n全部测试通过")
+}
+
+func testLocal() {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp("", "storage-test")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := storage.NewLocal(dir, "https://cdn.example.com/shots")
+	check("创建本地存储", err == nil, err)
+	testStorage(ctx, s, func(key string, age time.Duration) {
+		path := dir + "/" + key
+		os.Chtimes(path, time.Now().Add(-age), time.Now().Add(-age))
+	})
+
+	obj, err := s.Put(ctx, "a b/c.png", []byte("x"), "image/png")
+	check("访问地址转义特殊字符", err == nil && obj.URL == "https://cdn.example.com/shots/a%20b/c.png", obj)
+	for _, key := range []string{"../escape.png", "/abs.png", "a//b.png", `a\b.png`} {
+		_, err := s.Put(ctx, key, []byte("x"), "image/png")
+		check("拒绝无效的键 "+key, err != nil)
+	}
+	s.Delete(ctx, "a b/c.png")
+	entries, _ := os.ReadDir(dir)
+	check("删除后清理空目录", len(entries) == 0, entries)
+}
+
+func testS3() {
+	ctx := context.Background()
+	fake := newFakeS3()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	options := storage.S3Options{
+		Endpoint:  server.URL,
+		Region:    fakeRegion,
+		Bucket:    fakeBucket,
+		AccessKey: fakeAccessKey,
+		SecretKey: fakeSecretKey,
+		PathStyle: true,
+	}
+	s, err := storage.NewS3(options)
+	check("创建S3存储", err == nil, err)
+	testStorage(ctx, s, fake.age)
+
+	obj, err := s.Put(ctx, "etag.png", []byte("hello"), "image/png")
+	sum := md5.Sum([]byte("hello"))
+	check("返回ETag", err == nil && obj.ETag == `"`+hex.EncodeToString(sum[:])+`"`, obj)
+	check("默认使用对象的S3地址", obj != nil && obj.URL == server.URL+"/"+fakeBucket+"/etag.png", obj)
+	check("保存Content-Type", fake.contentType("etag.png") == "image/png", fake.contentType("etag.png"))
+
+	_, err = s.Put(ctx, "a b/c+d.png", []byte("x"), "image/png")
+	check("键中的特殊字符参与签名", err == nil, err)
+	s.Delete(ctx, "a b/c+d.png")
+
+	// 超过一页的对象列表
+	for i := 0; i < 5; i++ {
+		s.Put(ctx, fmt.Sprintf("page/%d.png", i), []byte("x"), "image/png")
+	}
+	objects, err := s.List(ctx, "page/")
+	check("分页列出对象", err == nil && len(objects) == 5 && fake.listCalls > 2, len(objects), err)
+
+	options.SecretKey = "wrong"
+	bad, _ := storage.NewS3(options)
+	_, err = bad.Put(ctx, "bad.png", []byte("x"), "image/png")
+	check("错误的密钥返回签名错误", err != nil && strings.Contains(err.Error(), "SignatureDoesNotMatch"), err)
+
+	options.SecretKey = fakeSecretKey
+	options.Bucket = "missing"
+	missing, _ := storage.NewS3(options)
+	_, err = missing.List(ctx, "")
+	check("不存在的存储桶返回错误", err != nil && strings.Contains(err.Error(), "NoSuchBucket"), err)
+
+	options.Bucket = fakeBucket
+	options.PublicURL = "https://cdn.example.com"
+	public, _ := storage.NewS3(options)
+	obj, err = public.Put(ctx, "public.png", []byte("x"), "image/png")
+	check("使用公开访问地址", err == nil && obj.URL == "https://cdn.example.com/public.png", obj)
+}
+
+// testStorage 两种存储共用的测试，setAge 修改对象的创建时间
+func testStorage(ctx context.Context, s storage.Storage, setAge func(key string, age time.Duration)) {
+	data := []byte("\x89PNG test image")
+	obj, err := s.Put(ctx, "2025-01-01/example.com-1.png", data, "image/png")
+	check("保存对象", err == nil && obj.Size == int64(len(data)), err)
+
+	got, err := s.Get(ctx, "2025-01-01/example.com-1.png")
+	check("读取对象", err == nil && bytes.Equal(got, data), err)
+
+	_, err = s.Get(ctx, "2025-01-01/missing.png")
+	check("读取不存在的对象返回 ErrNotFound", errors.Is(err, storage.ErrNotFound), err)
+
+	s.Put(ctx, "2025-01-01/example.com-2.png", data, "image/png")
+	s.Put(ctx, "2025-01-02/example.com-3.png", data, "image/png")
+	objects, err := s.List(ctx, "2025-01-01/")
+	check("按前缀列出对象", err == nil && len(objects) == 2, len(objects), err)
+
+	// 保留期限：只删除早于期限的对象
+	setAge("2025-01-01/example.com-1.png", 48*time.Hour)
+	setAge("2025-01-01/example.com-2.png", 2*time.Hour)
+	n, err := storage.Cleanup(ctx, s, "2025-", time.Now().Add(-24*time.Hour))
+	check("清理过期对象", err == nil && n == 1, n, err)
+	_, err = s.Get(ctx, "2025-01-01/example.com-1.png")
+	check("过期对象已删除", errors.Is(err, storage.ErrNotFound), err)
+	_, err = s.Get(ctx, "2025-01-01/example.com-2.png")
+	check("未过期对象仍然保留", err == nil, err)
+
+	err = s.Delete(ctx, "2025-01-01/example.com-2.png")
+	check("删除对象", err == nil, err)
+	check("删除不存在的对象不报错", s.Delete(ctx, "2025-01-01/example.com-2.png") == nil)
+	s.Delete(ctx, "2025-01-02/example.com-3.png")
+}
+
+func testTemplate() {
+	at := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+	t, err := storage.ParseTemplate("shots/{date}/{host}/{path}-{time}.{ext}")
+	check("解析模板", err == nil, err)
+	key, err := t.Key(storage.Vars{URL: "https://WWW.Example.com:8443/blog/post 1?x=1", Extension: "png", Time: at})
+	check("生成键", err == nil && key == "shots/2025-03-04/www.example.com/blog-post-1-050607.png", key)
+
+	t, _ = storage.ParseTemplate("{hash}.{ext}")
+	a, _ := t.Key(storage.Vars{Data: []byte("a"), Extension: "png"})
+	b, _ := t.Key(storage.Vars{Data: []byte("a"), Extension: "png"})
+	check("相同内容的哈希相同", a == b && len(a) == 20, a, b)
+
+	t, _ = storage.ParseTemplate(storage.DefaultTemplate)
+	a, _ = t.Key(storage.Vars{URL: "https://example.com", Extension: "png", Time: at})
+	b, _ = t.Key(storage.Vars{URL: "https://example.com", Extension: "png", Time: at})
+	check("随机ID不重复", a != b && strings.HasPrefix(a, "2025-03-04/example.com-"), a, b)
+
+	for _, value := range []string{"{name}.png", "{id", "../{id}.png", "/{id}"} {
+		_, err := storage.ParseTemplate(value)
+		check("拒绝无效的模板 "+value, err != nil)
+	}
+}
+
+// fakeS3 内存中的S3兼容服务，校验签名V4，支持 PUT、GET、DELETE 和 ListObjectsV2
+type fakeS3 struct {
+	mu        sync.Mutex
+	objects   map[string]*fakeObject
+	listCalls int
+}
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+	modified    time.Time
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: map[string]*fakeObject{}}
+}
+
+func (f *fakeS3) age(key string, age time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if obj := f.objects[key]; obj != nil {
+		obj.modified = time.Now().Add(-age)
+	}
+}
+
+func (f *fakeS3) contentType(key string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if obj := f.objects[key]; obj != nil {
+		return obj.contentType
+	}
+	return ""
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if code, msg := verifySignature(r, body); code != "" {
+		s3Error(w, http.StatusForbidden, code, msg)
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != fakeBucket {
+		s3Error(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case key == "" && r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+		f.list(w, r)
+	case r.Method == http.MethodPut:
+		f.objects[key] = &fakeObject{data: body, contentType: r.Header.Get("Content-Type"), modified: time.Now()}
+		w.Header().Set("ETag", etag(body))
+	case r.Method == http.MethodGet:
+		obj := f.objects[key]
+		if obj == nil {
+			s3Error(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Write(obj.data)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+	}
+}
+
+// list 每页最多2个对象，用于测试分页
+func (f *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	f.listCalls++
+	prefix := r.URL.Query().Get("prefix")
+	after := r.URL.Query().Get("continuation-token")
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) && key > after {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	type content struct {
+		Key          string
+		LastModified string
+		ETag         string
+		Size         int
+	}
+	result := struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		Contents              []content
+		IsTruncated           bool
+		NextContinuationToken string `xml:",omitempty"`
+	}{}
+	if len(keys) > 2 {
+		keys = keys[:2]
+		result.IsTruncated = true
+		result.NextContinuationToken = keys[1]
+	}
+	for _, key := range keys {
+		obj := f.objects[key]
+		result.Contents = append(result.Contents, content{
+			Key:          key,
+			LastModified: obj.modified.UTC().Format("2006-01-02T15:04:05.000Z"),
+			ETag:         etag(obj.data),
+			Size:         len(obj.data),
+		})
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+// verifySignature 按服务端的方式重新计算签名V4，失败时返回错误码
+func verifySignature(r *http.Request, body []byte) (string, string) {
+	auth := strings.TrimPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+	fields := map[string]string{}
+	for _, part := range strings.Split(auth, ", ") {
+		k, v, _ := strings.Cut(part, "=")
+		fields[k] = v
+	}
+	scope := strings.Split(fields["Credential"], "/")
+	if len(scope) != 5 || scope[0] != fakeAccessKey || scope[2] != fakeRegion || scope[3] != "s3" {
+		return "InvalidAccessKeyId", fields["Credential"]
+	}
+	if r.Header.Get("X-Amz-Content-Sha256") != hashHex(body) {
+		return "XAmzContentSHA256Mismatch", "payload hash mismatch"
+	}
+
+	var headers strings.Builder
+	signed := strings.Split(fields["SignedHeaders"], ";")
+	for _, name := range signed {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	query := r.URL.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var params []string
+	for _, k := range keys {
+		params = append(params, awsEscape(k)+"="+awsEscape(query.Get(k)))
+	}
+	canonical := strings.Join([]string{r.Method, r.URL.EscapedPath(), strings.Join(params, "&"),
+		headers.String(), fields["SignedHeaders"], hashHex(body)}, "\n")
+
+	date := r.Header.Get("X-Amz-Date")
+	stringToSign := "AWS4-HMAC-SHA256\n" + date + "\n" + strings.Join(scope[1:], "/") + "\n" + hashHex([]byte(canonical))
+	key := []byte("AWS4" + fakeSecretKey)
+	for _, s := range []string{scope[1], scope[2], scope[3], scope[4]} {
+		key = hmacSum(key, s)
+	}
+	if hex.EncodeToString(hmacSum(key, stringToSign)) != fields["Signature"] {
+		return "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided."
+	}
+	return "", ""
+}
+
+func awsEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSum(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func s3Error(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, message)
+}